@@ -0,0 +1,211 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrAttached alias 已被 Attach 或不是合法的标识符
+var ErrAttached = errors.New("sqlite: alias already attached or invalid")
+
+// attachment Attach 附加的数据库
+type attachment struct {
+	alias string
+	path  string
+}
+
+// connector 打开连接并附加所有 Attach 的数据库
+type connector struct {
+	drv driver.Driver
+	dsn string
+
+	mu       sync.Mutex
+	attached []attachment // 写时复制
+}
+
+// newconnector 返回以 DriverName 打开 dsn 的 connector
+func newconnector(dsn string) (*connector, error) {
+	d, err := sql.Open(DriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := d.Driver()
+	_ = d.Close()
+	return &connector{drv: drv, dsn: dsn}, nil
+}
+
+// Connect 实现 driver.Connector
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.drv.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	attached := c.attached
+	c.mu.Unlock()
+	for _, a := range attached {
+		err = attachconn(conn, a)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Driver 实现 driver.Connector
+func (c *connector) Driver() driver.Driver {
+	return c.drv
+}
+
+// set 替换附加的数据库列表
+func (c *connector) set(attached []attachment) {
+	c.mu.Lock()
+	c.attached = attached
+	c.mu.Unlock()
+}
+
+func (c *connector) list() []attachment {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attached
+}
+
+// attachconn 在 conn 上附加 a
+func attachconn(conn driver.Conn, a attachment) error {
+	stmt, err := conn.Prepare(attachsql(a.alias))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	// 连接尚未交给 database/sql, 只能直接调用驱动
+	_, err = stmt.Exec([]driver.Value{a.path})
+	return err
+}
+
+func attachsql(alias string) string {
+	return "ATTACH DATABASE ? AS " + quoteident(alias) + ";"
+}
+
+// Attach 以 alias 将 path 处的数据库附加到连接池中的每个连接,
+// 之后可用 alias.table 作为表名, 或在 Query 中跨库联合查询.
+// 等待进行中的操作结束, 期间其它操作返回 ErrClosed. 关闭数据库后失效.
+func (db *Sqlite) Attach(alias, path string) error {
+	if err := db.checkref(); err != nil {
+		return err
+	}
+	db = db.root()
+	if !isident(alias) {
+		return ErrAttached
+	}
+	return db.exclusive(func() error {
+		old := db.connector.list()
+		for _, a := range old {
+			if a.alias == alias {
+				return ErrAttached
+			}
+		}
+		attached := make([]attachment, len(old), len(old)+1)
+		copy(attached, old)
+		attached = append(attached, attachment{alias: alias, path: path})
+		db.connector.set(attached)
+		err := db.reconnect("Attach", alias, attachsql(alias), path)
+		if err != nil {
+			db.connector.set(old)
+			_ = db.reconnect("Detach", alias, "DETACH DATABASE "+quoteident(alias)+";")
+		}
+		return err
+	})
+}
+
+// Detach 取消 Attach 的 alias
+func (db *Sqlite) Detach(alias string) error {
+	if err := db.checkref(); err != nil {
+		return err
+	}
+	db = db.root()
+	return db.exclusive(func() error {
+		old := db.connector.list()
+		attached := make([]attachment, 0, len(old))
+		for _, a := range old {
+			if a.alias != alias {
+				attached = append(attached, a)
+			}
+		}
+		if len(attached) == len(old) {
+			return errors.New("sqlite: no such attached database " + alias)
+		}
+		db.connector.set(attached)
+		return db.reconnect("Detach", alias, "DETACH DATABASE "+quoteident(alias)+";")
+	})
+}
+
+// reconnect 在 exclusive 中调用, 丢弃空闲连接使其按新的附加列表重建,
+// 并在常驻连接上执行 q. 最后打开一个连接以检查附加是否成功.
+func (db *Sqlite) reconnect(op, alias, q string, args ...any) error {
+	db.stmtcache.clear()
+	db.db.SetMaxIdleConns(0)
+	db.db.SetMaxIdleConns(db.maxidleconns())
+	if db.keepalive != nil {
+		_, err := db.execwith(op, alias, q, args, func(ctx context.Context) (sql.Result, error) {
+			return db.keepalive.ExecContext(ctx, q, args...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	conn, err := db.db.Conn(db.context())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// tablename 返回 SQL 中的表名.
+// table 以 main, temp 或 Attach 的 alias 加 . 开头时视为 schema.table, 否则整体视为表名.
+func (db *Sqlite) tablename(table string) string {
+	if i := strings.IndexByte(table, '.'); i > 0 && i < len(table)-1 && db.isschema(table[:i]) {
+		return quoteident(table[:i]) + "." + wraptable(table[i+1:])
+	}
+	return wraptable(table)
+}
+
+// isschema 判断 name 是否为 main, temp 或 Attach 的 alias
+func (db *Sqlite) isschema(name string) bool {
+	if strings.EqualFold(name, "main") || strings.EqualFold(name, "temp") {
+		return true
+	}
+	c := db.root().connector
+	if c == nil {
+		return false
+	}
+	for _, a := range c.list() {
+		if strings.EqualFold(a.alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultmaxidle database/sql 默认的最大空闲连接数
+const defaultmaxidle = 2
+
+// WithMaxIdleConns 设置连接池最多保留的空闲连接数, n <= 0 时为 database/sql 的默认值 2.
+// Attach 与 Detach 丢弃空闲连接后恢复该设置.
+func WithMaxIdleConns(n int) Option {
+	return func(db *Sqlite) {
+		db.maxidle = n
+	}
+}
+
+// maxidleconns 返回连接池最多保留的空闲连接数
+func (db *Sqlite) maxidleconns() int {
+	if db.maxidle <= 0 {
+		return defaultmaxidle
+	}
+	return db.maxidle
+}
@@ -0,0 +1,211 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAttach(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	otherpath := filepath.Join(filepath.Dir(dbpath), "other.db")
+	other := Sqlite{dbpath: otherpath}
+	err := other.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := New(dbpath, WithMaxIdleConns(4))
+	err = db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("score", &struct {
+		ID    int64
+		Score int
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("score", &struct {
+		ID    int64
+		Score int
+	}{1, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Attach("other", otherpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Attach("other", otherpath) != ErrAttached {
+		t.Fatal("attached twice")
+	}
+	err = db.Insert("other.user", &user{ID: 1, Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := Find[user](&db, "other.user", "WHERE ID = ?", 1)
+	if err != nil || u.Name != "Anna" {
+		t.Fatal(u, err)
+	}
+	n, err := db.Count("other.user")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	// 连接池中的每个连接都附加了 other
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conns[i], err = db.db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range conns {
+		var name string
+		err = conn.QueryRowContext(context.Background(),
+			"SELECT u.Name FROM other.user u JOIN main.score s ON u.ID = s.ID;").Scan(&name)
+		if err != nil || name != "Anna" {
+			t.Fatal(name, err)
+		}
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	// Restore 后仍然附加 other, 且保留空闲连接数的设置
+	err = db.Backup(filepath.Join(filepath.Dir(dbpath), "backup.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Restore(filepath.Join(filepath.Dir(dbpath), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = db.Count("other.user")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	conns = append(conns, nil)
+	for i := range conns {
+		conns[i], err = db.db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	if idle := db.PoolStats().Idle; idle != 4 {
+		t.Fatal("unexpected idle connections", idle)
+	}
+	// 前缀不是 schema 时整体视为表名
+	err = db.Create("v1.2", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("v1.2", &user{ID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = db.Count("v1.2")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	err = db.Detach("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Count("other.user")
+	if err == nil {
+		t.Fatal("detached database is still accessible")
+	}
+	err = db.Detach("other")
+	if err == nil {
+		t.Fatal("detached twice")
+	}
+	err = db.Attach("missing", "nonexistent/missing.db")
+	if err == nil {
+		t.Fatal("attached missing file")
+	}
+	n, err = db.Count("score")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+}
+
+func TestShared(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	a, err := Shared(dbpath, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Shared(filepath.Dir(dbpath)+"/./test.db", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.root() != b.root() {
+		t.Fatal("same file opened twice")
+	}
+	err = a.Create("user", &struct{ ID int64 }{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重复 Close 与视图的 Close 不释放其它引用
+	for i := 0; i < 2; i++ {
+		err = a.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = b.WithContext(context.Background()).Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Count("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.Count("user")
+	if err != ErrClosed {
+		t.Fatal("closed reference still usable, got", err)
+	}
+	err = a.Attach("other", filepath.Join(t.TempDir(), "other.db"))
+	if err != ErrClosed {
+		t.Fatal("closed reference attached, got", err)
+	}
+	err = a.StartJanitor(JanitorOptions{Interval: time.Hour})
+	if err != ErrClosed {
+		t.Fatal("closed reference started janitor, got", err)
+	}
+	err = b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Count("user")
+	if err != ErrClosed {
+		t.Fatal(err)
+	}
+	c, err := Shared(dbpath, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.root() == a.root() {
+		t.Fatal("closed db reused")
+	}
+}
@@ -0,0 +1,156 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// BackupOptions 在线备份选项
+type BackupOptions struct {
+	// PagesPerStep 每步复制的页数, <= 0 时一步复制全部.
+	PagesPerStep int
+	// StepInterval 每步之间的间隔, 期间释放读锁, 避免饿死写入者.
+	StepInterval time.Duration
+	// Progress 每步完成后回调, copied 为已复制页数, total 为总页数.
+	Progress func(copied, total int)
+	// Verify 完成后对备份文件执行 PRAGMA integrity_check.
+	Verify bool
+}
+
+type backuper interface {
+	NewBackup(string) (*sqlite.Backup, error)
+}
+
+// Backup 在线备份数据库到 dstpath, 备份期间数据库可正常读写.
+// 先写入同目录下的临时文件, 成功后再替换 dstpath.
+// opt 为 nil 时一步复制全部且不校验.
+func (db *Sqlite) Backup(dstpath string, opt *BackupOptions) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	if opt == nil {
+		opt = &BackupOptions{}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dstpath), filepath.Base(dstpath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmppath := tmp.Name()
+	_ = tmp.Close()
+	defer os.Remove(tmppath)
+	err = db.backup(tmppath, opt)
+	if err != nil {
+		return err
+	}
+	if opt.Verify {
+		err = verify(tmppath)
+		if err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmppath, dstpath)
+}
+
+// BackupTo 在线备份数据库并写入 w.
+// 返回写入的字节数+错误.
+func (db *Sqlite) BackupTo(w io.Writer, opt *BackupOptions) (int64, error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	dir, err := os.MkdirTemp("", "sqlite-backup-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "backup.db")
+	err = db.Backup(dst, opt)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+func (db *Sqlite) backup(dstpath string, opt *BackupOptions) error {
+	var total int
+	err := db.scanrow("Backup", "", "PRAGMA page_count;", &total)
+	if err != nil {
+		return err
+	}
+	step := int32(-1)
+	if opt.PagesPerStep > 0 {
+		step = int32(opt.PagesPerStep)
+	}
+	conn, err := db.root().db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		bc, ok := dc.(backuper)
+		if !ok {
+			return errors.New("sqlite: driver does not support online backup")
+		}
+		b, err := bc.NewBackup(dstpath)
+		if err != nil {
+			return err
+		}
+		copied := 0
+		for more := true; more; {
+			more, err = b.Step(step)
+			if err != nil {
+				_ = b.Finish()
+				return err
+			}
+			if step < 0 || !more {
+				copied = total
+			} else if copied += int(step); copied > total {
+				copied = total
+			}
+			if opt.Progress != nil {
+				opt.Progress(copied, total)
+			}
+			if more && opt.StepInterval > 0 {
+				time.Sleep(opt.StepInterval)
+			}
+		}
+		return b.Finish()
+	})
+}
+
+// verify 对 path 处的数据库执行 PRAGMA integrity_check
+func verify(path string) error {
+	d, err := sql.Open(DriverName, path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return integrity(d)
+}
+
+// integrity 执行 PRAGMA integrity_check
+func integrity(d *sql.DB) error {
+	var result string
+	err := d.QueryRow("PRAGMA integrity_check;").Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s", ErrCorrupt, result)
+	}
+	return nil
+}
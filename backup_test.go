@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	type counter struct {
+		ID    *int
+		Count uint
+		Note  string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("counter", &counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 512; i++ {
+		err = db.Insert("counter", &counter{Count: uint(i), Note: strconv.Itoa(i) + string(make([]byte, 256))})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := filepath.Join(t.TempDir(), "backup.db")
+	steps := 0
+	err = db.Backup(dst, &BackupOptions{
+		PagesPerStep: 16,
+		StepInterval: time.Millisecond,
+		Progress: func(copied, total int) {
+			steps++
+			if copied > total {
+				t.Error("copied", copied, "> total", total)
+			}
+		},
+		Verify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps < 2 {
+		t.Fatal("unexpected steps", steps)
+	}
+	bak := Sqlite{dbpath: dst}
+	err = bak.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bak.Close()
+	n, err := bak.Count("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 512 {
+		t.Fatal("unexpected count", n)
+	}
+	var buf bytes.Buffer
+	sz, err := db.BackupTo(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz == 0 || !bytes.HasPrefix(buf.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Fatal("unexpected backup size", sz)
+	}
+}
@@ -0,0 +1,80 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// batchinserter 分批在事务中插入数据
+type batchinserter struct {
+	db    *Sqlite
+	op    string // 上报执行信息时的操作名
+	table string
+	size  int // 每个事务的行数
+
+	cols    []string
+	tx      *sql.Tx
+	stmt    *sql.Stmt
+	pending int // 当前事务中已插入的行数
+	n       int // 已提交的行数
+}
+
+// insert 在当前事务中插入 objptr, 满 size 行时提交.
+// 单行出错不影响同一事务中的其它行.
+func (b *batchinserter) insert(objptr any) (err error) {
+	if b.cols == nil {
+		b.cols, err = b.db.columns(b.op, b.table)
+		if err != nil {
+			return
+		}
+	}
+	q, vals := insertsql("REPLACE INTO", b.db.tablename(b.table), b.cols, objptr)
+	if b.tx == nil {
+		// 先编译再开启事务, 编译失败时不留下没有语句的事务
+		stmt, err := b.db.compile(q)
+		if err != nil {
+			return err
+		}
+		defer stmt.release()
+		tx, err := b.db.root().db.BeginTx(b.db.context(), nil)
+		if err != nil {
+			return err
+		}
+		b.tx, b.stmt = tx, tx.Stmt(stmt.stmt)
+	}
+	_, err = b.db.execwith(b.op, b.table, q, vals, func(ctx context.Context) (sql.Result, error) {
+		return b.stmt.ExecContext(ctx, vals...)
+	})
+	if err != nil {
+		return
+	}
+	b.pending++
+	if b.pending >= b.size {
+		return b.commit()
+	}
+	return
+}
+
+// commit 提交当前事务
+func (b *batchinserter) commit() error {
+	if b.tx == nil {
+		return nil
+	}
+	tx := b.tx
+	b.tx, b.stmt = nil, nil
+	err := b.db.endtx(b.op, tx, true)
+	if err == nil {
+		b.n += b.pending
+	}
+	b.pending = 0
+	return err
+}
+
+// rollback 回滚未提交的事务
+func (b *batchinserter) rollback() {
+	if b.tx == nil {
+		return
+	}
+	_ = b.db.endtx(b.op, b.tx, false)
+	b.tx, b.stmt, b.pending = nil, nil, 0
+}
@@ -0,0 +1,215 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Finding 完整性检查发现的一处问题
+type Finding struct {
+	Table   string // 所属表, 无法确定时为空
+	RowID   *int64 // 所在行, 无法确定或 WITHOUT ROWID 表时为 nil
+	Parent  string // 外键检查时被引用的父表
+	FKID    int    // 外键检查时外键在 PRAGMA foreign_key_list 中的 id
+	Message string
+}
+
+var (
+	findingrow   = regexp.MustCompile(`\brow (\d+)`)
+	findingindex = regexp.MustCompile(`\bindex (\S+)`)
+	findingtable = regexp.MustCompile(`(?:NULL value in|CHECK constraint failed in) ([^\s.]+)`)
+)
+
+// IntegrityCheck 执行 PRAGMA integrity_check.
+// 数据库完好时返回空切片.
+func (db *Sqlite) IntegrityCheck(ctx context.Context) ([]Finding, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	return db.WithContext(ctx).check("IntegrityCheck", "PRAGMA integrity_check;")
+}
+
+// QuickCheck 执行 PRAGMA quick_check, 比 IntegrityCheck 快但不检查索引内容.
+// 数据库完好时返回空切片.
+func (db *Sqlite) QuickCheck() ([]Finding, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	return db.check("QuickCheck", "PRAGMA quick_check;")
+}
+
+func (db *Sqlite) check(op, q string) ([]Finding, error) {
+	rows, err := db.querywith(op, "", q, nil, func(ctx context.Context) (*sql.Rows, error) {
+		return db.root().db.QueryContext(ctx, q)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var msgs []string
+	for rows.Next() {
+		var msg string
+		err = rows.Scan(&msg)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 || (len(msgs) == 1 && msgs[0] == "ok") {
+		return nil, nil
+	}
+	var owners map[string]string // 索引名 -> 表名
+	findings := make([]Finding, len(msgs))
+	for i, msg := range msgs {
+		f := &findings[i]
+		f.Message = msg
+		if m := findingrow.FindStringSubmatch(msg); m != nil {
+			if id, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+				f.RowID = &id
+			}
+		}
+		if m := findingtable.FindStringSubmatch(msg); m != nil {
+			f.Table = m[1]
+			continue
+		}
+		if m := findingindex.FindStringSubmatch(msg); m != nil {
+			if owners == nil {
+				owners, err = db.indexowners(op)
+				if err != nil {
+					return nil, err
+				}
+			}
+			f.Table = owners[m[1]]
+		}
+	}
+	return findings, nil
+}
+
+// indexowners 返回 索引名 -> 表名
+func (db *Sqlite) indexowners(op string) (map[string]string, error) {
+	rows, err := db.query(op, "", "SELECT name, tbl_name FROM sqlite_master WHERE type='index';")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	owners := make(map[string]string)
+	for rows.Next() {
+		var name, table string
+		err = rows.Scan(&name, &table)
+		if err != nil {
+			return nil, err
+		}
+		owners[name] = table
+	}
+	return owners, rows.Err()
+}
+
+// ForeignKeyCheck 执行 PRAGMA foreign_key_check.
+// table 为空时检查所有表. 没有违反外键约束的行时返回空切片.
+func (db *Sqlite) ForeignKeyCheck(table string) ([]Finding, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	type fkrow struct {
+		Table  string
+		RowID  *int64
+		Parent string
+		FKID   int
+	}
+	var (
+		rows []*fkrow
+		err  error
+	)
+	if table == "" {
+		rows, err = queryall[fkrow](db, "ForeignKeyCheck", "", "SELECT * FROM pragma_foreign_key_check;")
+	} else {
+		rows, err = queryall[fkrow](db, "ForeignKeyCheck", table, "SELECT * FROM pragma_foreign_key_check(?);", table)
+	}
+	if err == ErrNullResult {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]Finding, len(rows))
+	for i, r := range rows {
+		findings[i] = Finding{
+			Table: r.Table, RowID: r.RowID, Parent: r.Parent, FKID: r.FKID,
+			Message: "foreign key " + strconv.Itoa(r.FKID) + " violated, parent " + r.Parent,
+		}
+	}
+	return findings, nil
+}
+
+// startupcheck Open 时的检查选项
+type startupcheck struct {
+	dir string     // 损坏时从该目录的最新快照恢复, 为空时拒绝打开
+	c   Compressor // 快照的压缩算法
+}
+
+// WithQuickCheck Open 时执行 QuickCheck, 数据库损坏时关闭并返回 ErrCorrupt.
+func WithQuickCheck() Option {
+	return func(db *Sqlite) {
+		db.startcheck = &startupcheck{}
+	}
+}
+
+// WithAutoRestore Open 时执行 QuickCheck, 数据库损坏时从 dir 中最新的快照恢复.
+// c 为快照使用的压缩算法, 未压缩时为 nil. 恢复失败时关闭并返回错误.
+func WithAutoRestore(dir string, c Compressor) Option {
+	return func(db *Sqlite) {
+		db.startcheck = &startupcheck{dir: dir, c: c}
+	}
+}
+
+// checkstartup 执行 Open 时的检查, 损坏时按选项恢复或关闭数据库.
+// 繁忙, I/O 等其它错误原样返回且不恢复.
+func (db *Sqlite) checkstartup() error {
+	findings, err := db.QuickCheck()
+	switch {
+	case err == nil && len(findings) == 0:
+		return nil
+	case err == nil:
+		err = fmt.Errorf("%w: %s", ErrCorrupt, findings[0].Message)
+	case iscorrupt(err):
+		err = fmt.Errorf("%w: %v", ErrCorrupt, err)
+	default:
+		_ = db.Close()
+		return err
+	}
+	if db.startcheck.dir != "" {
+		rerr := db.RestoreSnapshot(db.startcheck.dir, db.startcheck.c)
+		if rerr == nil {
+			return nil
+		}
+		err = errors.Join(err, rerr)
+	}
+	_ = db.Close()
+	return err
+}
+
+const (
+	sqlitecorrupt = 11 // SQLITE_CORRUPT
+	sqlitenotadb  = 26 // SQLITE_NOTADB
+)
+
+// iscorrupt 判断 err 是否为 SQLITE_CORRUPT 或 SQLITE_NOTADB
+func iscorrupt(err error) bool {
+	var e interface{ Code() int }
+	if !errors.As(err, &e) {
+		return false
+	}
+	c := e.Code() & 0xff
+	return c == sqlitecorrupt || c == sqlitenotadb
+}
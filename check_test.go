@@ -0,0 +1,148 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestForeignKeyCheck(t *testing.T) {
+	type teacher struct {
+		ID   *int
+		Name string
+	}
+	type class struct {
+		ID        *int
+		TeacherID int `db:"TeacherID,references=teacher(ID)"`
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("teacher", &teacher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("class", &class{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("teacher", &teacher{Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("class", &class{TeacherID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("class", &class{TeacherID: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := db.ForeignKeyCheck("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Table != "class" || *findings[0].RowID != 2 || findings[0].Parent != "teacher" {
+		t.Fatal("unexpected", findings)
+	}
+	findings, err = db.ForeignKeyCheck("teacher")
+	if err != nil || len(findings) != 0 {
+		t.Fatal("unexpected", findings, err)
+	}
+	findings, err = db.IntegrityCheck(context.Background())
+	if err != nil || len(findings) != 0 {
+		t.Fatal("unexpected", findings, err)
+	}
+}
+
+func TestStartupCheck(t *testing.T) {
+	type counter struct {
+		ID    *int
+		Count uint
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour, WithQuickCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("counter", &counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 1024; i++ {
+		err = db.Insert("counter", &counter{Count: uint(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	err = db.StartSnapshots(SnapshotOptions{Dir: dir, Interval: time.Hour, Compressor: Gzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 破坏第 2 页之后的数据
+	f, err := os.OpenFile(dbpath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteAt(make([]byte, 4096*2), 4096)
+	_ = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 其它连接持有排他锁时只返回繁忙, 不从快照恢复
+	locker := Sqlite{dbpath: dbpath}
+	err = locker.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := locker.db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = Sqlite{dbpath: dbpath}
+	err = db.Open(time.Hour, WithAutoRestore(dir, Gzip))
+	if !isbusy(err) || errors.Is(err, ErrCorrupt) {
+		t.Fatal("unexpected", err)
+	}
+	_, _ = conn.ExecContext(context.Background(), "ROLLBACK;")
+	_ = conn.Close()
+	_ = locker.Close()
+	db = Sqlite{dbpath: dbpath}
+	err = db.Open(time.Hour, WithQuickCheck())
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatal("unexpected", err)
+	}
+	err = db.Open(time.Hour, WithAutoRestore(dir, Gzip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	n, err := db.Count("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1024 {
+		t.Fatal("unexpected count", n)
+	}
+}
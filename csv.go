@@ -0,0 +1,341 @@
+package sql
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBatchSize 默认导入时每个事务的行数
+const DefaultBatchSize = 1000
+
+// CSVOptions CSV 导入导出选项
+type CSVOptions struct {
+	// Comma 分隔符, 默认为 ','
+	Comma rune
+	// Null NULL 的表示, 默认为空串
+	Null string
+	// Header 导出时为 列名->表头, 导入时为 表头->列名
+	Header map[string]string
+	// NoHeader 导出时不写表头, 导入时无表头且按结构体字段顺序对应
+	NoHeader bool
+	// BatchSize 导入时每个事务的行数, 默认为 DefaultBatchSize
+	BatchSize int
+	// OnError 导入时的行错误回调, 返回 false 中止导入.
+	// 为 nil 时遇错中止并返回 *RowError.
+	OnError func(*RowError) bool
+}
+
+// RowError 导入时某一行的错误
+type RowError struct {
+	Line int // 在输入中的行号, 从 1 开始
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return "sqlite: line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+func (opt *CSVOptions) comma() rune {
+	if opt.Comma == 0 {
+		return ','
+	}
+	return opt.Comma
+}
+
+func (opt *CSVOptions) batch() int {
+	return batchsize(opt.BatchSize)
+}
+
+// batchsize 返回每个事务的行数, size <= 0 时为 DefaultBatchSize
+func batchsize(size int) int {
+	if size <= 0 {
+		return DefaultBatchSize
+	}
+	return size
+}
+
+// ExportCSV 将表或查询结果以 CSV 写入 w.
+// source 以 SELECT 或 WITH 开头时视为查询语句, args 为其参数, 否则视为表名.
+// BLOB 以 base64 编码, 时间以 RFC3339 编码.
+// 返回写入的行数+错误.
+func (db *Sqlite) ExportCSV(w io.Writer, source string, opt *CSVOptions, args ...any) (n int, err error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	if opt == nil {
+		opt = &CSVOptions{}
+	}
+	q, table := source, ""
+	if !isquery(source) {
+		q, table = "SELECT * FROM "+db.tablename(source)+";", source
+	}
+	rows, err := db.query("ExportCSV", table, q, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = opt.comma()
+	record := make([]string, len(cols))
+	if !opt.NoHeader {
+		for i, c := range cols {
+			record[i] = c
+			if h, ok := opt.Header[c]; ok {
+				record[i] = h
+			}
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return
+		}
+	}
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		err = rows.Scan(ptrs...)
+		if err != nil {
+			return
+		}
+		for i, v := range vals {
+			record[i] = csvstring(v, opt.Null)
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return
+		}
+		n++
+	}
+	err = rows.Err()
+	if err != nil {
+		return
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// isquery 判断 s 是否为查询语句
+func isquery(s string) bool {
+	f := strings.Fields(s)
+	return len(f) > 0 && (strings.EqualFold(f[0], "SELECT") || strings.EqualFold(f[0], "WITH"))
+}
+
+// csvstring 返回 v 在 CSV 中的表示
+func csvstring(v any, null string) string {
+	switch x := v.(type) {
+	case nil:
+		return null
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x)
+	case string:
+		return x
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return null
+}
+
+// ImportCSV 从 r 读取 CSV 并插入 table, 表头按 T 的列名对应.
+// 每 BatchSize 行提交一次事务, 已提交的批次在出错时不会回滚.
+// 返回成功插入的行数+错误.
+func ImportCSV[T any](db *Sqlite, table string, r io.Reader, opt *CSVOptions) (int, error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	if opt == nil {
+		opt = &CSVOptions{}
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = opt.comma()
+	cr.ReuseRecord = true
+	var obj T
+	fs := fieldsof(reflect.TypeOf(&obj).Elem())
+	var mapping []int // CSV 列 -> 字段下标
+	if opt.NoHeader {
+		mapping = make([]int, len(fs))
+		for i := range mapping {
+			mapping[i] = i
+		}
+	} else {
+		header, err := cr.Read()
+		if err != nil {
+			return 0, err
+		}
+		mapping, err = csvmapping(header, fs, opt.Header)
+		if err != nil {
+			return 0, err
+		}
+	}
+	b := &batchinserter{db: db, op: "ImportCSV", table: table, size: opt.batch()}
+	defer b.rollback()
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line := 0
+		if perr, ok := err.(*csv.ParseError); ok {
+			line = perr.Line
+		} else if err == nil {
+			line, _ = cr.FieldPos(0)
+		}
+		if err == nil {
+			var zero T
+			obj = zero
+			err = parserecord(&obj, fs, mapping, record, opt.Null)
+		}
+		if err == nil {
+			err = b.insert(&obj)
+		}
+		if err != nil {
+			rerr := &RowError{Line: line, Err: err}
+			if opt.OnError == nil || !opt.OnError(rerr) {
+				return b.n, rerr
+			}
+			continue
+		}
+	}
+	err := b.commit()
+	return b.n, err
+}
+
+// csvmapping 按表头建立 CSV 列到字段下标的映射
+func csvmapping(header []string, fs []field, rename map[string]string) ([]int, error) {
+	mapping := make([]int, len(header))
+	for i, h := range header {
+		name := h
+		if c, ok := rename[h]; ok {
+			name = c
+		}
+		mapping[i] = -1
+		for j, f := range fs {
+			if strings.EqualFold(f.name, name) {
+				mapping[i] = j
+				break
+			}
+		}
+		if mapping[i] < 0 {
+			return nil, errors.New("sqlite: unknown csv column " + strconv.Quote(h))
+		}
+	}
+	return mapping, nil
+}
+
+// parserecord 将一行 CSV 写入 objptr
+func parserecord(objptr any, fs []field, mapping []int, record []string, null string) error {
+	elem := reflect.ValueOf(objptr).Elem()
+	for i, s := range record {
+		if i >= len(mapping) || fs[mapping[i]].readonly {
+			continue
+		}
+		f := &fs[mapping[i]]
+		err := parsevalue(elem.FieldByIndex(f.index), s, s == null)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+var typtime = reflect.TypeOf(time.Time{})
+
+// parsevalue 将 s 按 fv 的类型解析并写入 fv
+func parsevalue(fv reflect.Value, s string, isnull bool) error {
+	switch fv.Kind() {
+	case reflect.Pointer:
+		if isnull {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		p := reflect.New(fv.Type().Elem())
+		err := parsevalue(p.Elem(), s, false)
+		if err == nil {
+			fv.Set(p)
+		}
+		return err
+	case reflect.Slice:
+		if isnull {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		switch fv.Type().Elem().Kind() {
+		case reflect.Uint8:
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err
+			}
+			fv.SetBytes(b)
+			return nil
+		case reflect.String:
+			fv.Set(reflect.ValueOf([]string{s}))
+			return nil
+		}
+	}
+	if isnull {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+	if fv.Type() == typtime {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err == nil {
+			fv.Set(reflect.ValueOf(t))
+		}
+		return err
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return errors.New("unsupported type " + fv.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sql
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSV(t *testing.T) {
+	type user struct {
+		ID    *int
+		Name  string
+		Score *float64
+		Data  []byte
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "uid;Name;Score;Data\n" +
+		"1;\"Anna; A\";1.5;AQID\n" +
+		"2;Bob;NULL;\n" +
+		"x;Bad;0;\n" +
+		"4;Dan;2;\n"
+	var rowerrs []*RowError
+	n, err := ImportCSV[user](&db, "user", strings.NewReader(input), &CSVOptions{
+		Comma:     ';',
+		Null:      "NULL",
+		Header:    map[string]string{"uid": "ID"},
+		BatchSize: 2,
+		OnError: func(e *RowError) bool {
+			rowerrs = append(rowerrs, e)
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || len(rowerrs) != 1 || rowerrs[0].Line != 4 {
+		t.Fatal("unexpected", n, rowerrs)
+	}
+	u, err := Find[user](&db, "user", "WHERE ID = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "Bob" || u.Score != nil {
+		t.Fatal("unexpected", u)
+	}
+	var buf bytes.Buffer
+	n, err = db.ExportCSV(&buf, "user", &CSVOptions{Null: "NULL", Header: map[string]string{"ID": "uid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "uid,Name,Score,Data\n1,Anna; A,1.5,AQID\n2,Bob,NULL,\n4,Dan,2,\n"
+	if n != 3 || buf.String() != expected {
+		t.Fatal("unexpected", n, buf.String())
+	}
+	buf.Reset()
+	n, err = db.ExportCSV(&buf, "SELECT Name FROM user WHERE ID > ?", &CSVOptions{NoHeader: true}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || buf.String() != "Bob\nDan\n" {
+		t.Fatal("unexpected", n, buf.String())
+	}
+	_, err = ImportCSV[user](&db, "user", strings.NewReader("ID,Name,Score,Data\n5,Eve,bad,\n"), nil)
+	var rerr *RowError
+	if !errors.As(err, &rerr) || rerr.Line != 2 {
+		t.Fatal("unexpected", err)
+	}
+	if db.CanFind("user", "WHERE ID = 5") {
+		t.Fatal("unexpected row")
+	}
+}
@@ -0,0 +1,175 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// constraints 解析字段 tag 中的附加选项, 返回列约束.
+//
+// 支持的选项:
+//
+//	default=值             DEFAULT 值
+//	check=表达式           CHECK (表达式)
+//	collate=名称           COLLATE 名称
+//	references=表(列)      REFERENCES 表(列)
+//	on_delete=动作         ON DELETE 动作, 需配合 references
+//	on_update=动作         ON UPDATE 动作, 需配合 references
+//	generated=表达式 stored GENERATED ALWAYS AS (表达式) STORED, 可选 virtual
+//
+// 其余不含 = 的选项按原样拼接, 如 UNIQUE.
+// raw 为 false 时忽略原样拼接的选项.
+func (f *field) constraints(raw bool) (cons []string, err error) {
+	var refs, ondelete, onupdate string
+	for _, o := range f.opts {
+		key, val, ok := cutopt(o)
+		if !ok {
+			if raw {
+				cons = append(cons, o)
+			}
+			continue
+		}
+		if val == "" {
+			return nil, fmt.Errorf("%w: %s: empty %s", ErrInvalidTag, f.name, key)
+		}
+		switch key {
+		case "default":
+			if !isliteral(val) {
+				val = "(" + val + ")"
+			}
+			cons = append(cons, "DEFAULT", val)
+		case "check":
+			cons = append(cons, "CHECK ("+val+")")
+		case "collate":
+			if !isident(val) {
+				return nil, fmt.Errorf("%w: %s: invalid collation %q", ErrInvalidTag, f.name, val)
+			}
+			cons = append(cons, "COLLATE", strings.ToUpper(val))
+		case "references":
+			t, c, ok := cutref(val)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s: invalid reference %q", ErrInvalidTag, f.name, val)
+			}
+			refs = "REFERENCES " + wraptable(t)
+			if c != "" {
+				refs += "(" + c + ")"
+			}
+		case "on_delete", "on_update":
+			act, ok := fkaction(val)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s: invalid %s action %q", ErrInvalidTag, f.name, key, val)
+			}
+			if key == "on_delete" {
+				ondelete = act
+			} else {
+				onupdate = act
+			}
+		case "generated":
+			expr, typ := val, "VIRTUAL"
+			if i := strings.LastIndexFunc(val, unicode.IsSpace); i > 0 {
+				switch strings.ToUpper(val[i+1:]) {
+				case "STORED":
+					expr, typ = strings.TrimSpace(val[:i]), "STORED"
+				case "VIRTUAL":
+					expr = strings.TrimSpace(val[:i])
+				}
+			}
+			cons = append(cons, "GENERATED ALWAYS AS ("+expr+")", typ)
+		default:
+			return nil, fmt.Errorf("%w: %s: unknown option %q", ErrInvalidTag, f.name, key)
+		}
+	}
+	if refs == "" {
+		if ondelete != "" || onupdate != "" {
+			return nil, fmt.Errorf("%w: %s: on_delete/on_update without references", ErrInvalidTag, f.name)
+		}
+		return
+	}
+	cons = append(cons, refs)
+	if ondelete != "" {
+		cons = append(cons, "ON DELETE", ondelete)
+	}
+	if onupdate != "" {
+		cons = append(cons, "ON UPDATE", onupdate)
+	}
+	return
+}
+
+// cutopt 切分 key=value 形式的选项, key 须为小写标识符
+func cutopt(o string) (key, val string, ok bool) {
+	key, val, ok = strings.Cut(o, "=")
+	if !ok {
+		return
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !isident(key) {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(val), true
+}
+
+// cutref 切分 table(col) 形式的外键引用, col 可省略
+func cutref(s string) (table, col string, ok bool) {
+	table, col, hascol := strings.Cut(s, "(")
+	table = strings.TrimSpace(table)
+	if table == "" || strings.ContainsAny(table, " '\"[]") {
+		return "", "", false
+	}
+	if !hascol {
+		return table, "", true
+	}
+	col, rest, ok := strings.Cut(col, ")")
+	if !ok || strings.TrimSpace(rest) != "" {
+		return "", "", false
+	}
+	for _, c := range strings.Split(col, ",") {
+		if !isident(strings.TrimSpace(c)) {
+			return "", "", false
+		}
+	}
+	return table, col, true
+}
+
+// fkaction 规范化外键动作
+func fkaction(s string) (string, bool) {
+	act := strings.ToUpper(strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || unicode.IsSpace(r)
+	}), " "))
+	switch act {
+	case "CASCADE", "RESTRICT", "NO ACTION", "SET NULL", "SET DEFAULT":
+		return act, true
+	}
+	return "", false
+}
+
+// isliteral 判断 s 是否可直接作为 DEFAULT 的字面量, 否则需加括号
+func isliteral(s string) bool {
+	switch strings.ToUpper(s) {
+	case "NULL", "TRUE", "FALSE", "CURRENT_TIME", "CURRENT_DATE", "CURRENT_TIMESTAMP":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		return true
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "X"), "x")
+	return len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' && !strings.Contains(s[1:len(s)-1], "'")
+}
+
+// isident 判断 s 是否为合法的标识符
+func isident(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
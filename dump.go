@@ -0,0 +1,436 @@
+package sql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dump 以 SQL 脚本的形式导出数据库, 类似 sqlite3 的 .dump.
+// tables 为空时导出所有表, 否则只导出指定的表及其索引, 触发器与视图.
+// 所有表在同一读事务中导出, 导出期间的写入不会使结果不一致.
+// 返回错误.
+func (db *Sqlite) Dump(w io.Writer, tables ...string) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	tx, err := db.root().db.BeginTx(db.context(), nil)
+	if err != nil {
+		return err
+	}
+	err = db.dump(tx, w, tables)
+	if err != nil {
+		_ = db.endtx("Dump", tx, false)
+		return err
+	}
+	return db.endtx("Dump", tx, true)
+}
+
+func (db *Sqlite) dump(tx *sql.Tx, w io.Writer, tables []string) error {
+	type object struct {
+		Type    string
+		Name    string
+		TblName string
+		SQL     string
+	}
+	objs, err := scanall[object](db.txquery(tx, "Dump", "", "SELECT type, name, tbl_name, sql FROM sqlite_master "+
+		"WHERE sql NOT NULL AND name NOT LIKE 'sqlite_%' "+
+		"ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, rowid;"))
+	if err != nil && err != ErrNullResult {
+		return err
+	}
+	want := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		want[t] = true
+	}
+	bw := bufio.NewWriter(w)
+	_, _ = bw.WriteString("PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n")
+	seq := false
+	for _, o := range objs {
+		// 计数器紧随所有表之后, 早于索引与触发器
+		if o.Type != "table" && !seq {
+			seq = true
+			err = db.dumpsequence(tx, bw, want)
+			if err != nil {
+				return err
+			}
+		}
+		if len(want) > 0 && !want[o.TblName] && !want[o.Name] {
+			continue
+		}
+		_, _ = bw.WriteString(o.SQL)
+		_, _ = bw.WriteString(";\n")
+		if o.Type == "table" && !strings.HasPrefix(strings.ToUpper(o.SQL), "CREATE VIRTUAL") {
+			err = db.dumprows(tx, bw, o.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !seq {
+		err = db.dumpsequence(tx, bw, want)
+		if err != nil {
+			return err
+		}
+	}
+	_, _ = bw.WriteString("COMMIT;\n")
+	return bw.Flush()
+}
+
+// txquery 在 tx 中执行 q, 在结果关闭时上报执行信息
+func (db *Sqlite) txquery(tx *sql.Tx, op, table, q string, args ...any) (*rows, error) {
+	return db.querywith(op, table, q, args, func(ctx context.Context) (*sql.Rows, error) {
+		return tx.QueryContext(ctx, q, args...)
+	})
+}
+
+// dumpsequence 写入 AUTOINCREMENT 计数器, 使导入后的自增值延续原数据库.
+// want 非空时只写入其中的表, 不影响其它表的计数器.
+func (db *Sqlite) dumpsequence(tx *sql.Tx, w *bufio.Writer, want map[string]bool) error {
+	type count struct{ N int }
+	c, err := scanall[count](db.txquery(tx, "Dump", "sqlite_sequence",
+		"SELECT COUNT(1) FROM sqlite_master WHERE name = 'sqlite_sequence';"))
+	if err != nil || c[0].N == 0 {
+		return err
+	}
+	type sequence struct {
+		Name string
+		Seq  int64
+	}
+	seqs, err := scanall[sequence](db.txquery(tx, "Dump", "sqlite_sequence", "SELECT name, seq FROM sqlite_sequence;"))
+	if err == ErrNullResult {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(want) == 0 {
+		_, _ = w.WriteString("DELETE FROM sqlite_sequence;\n")
+	}
+	for _, s := range seqs {
+		if len(want) > 0 {
+			if !want[s.Name] {
+				continue
+			}
+			_, _ = w.WriteString("DELETE FROM sqlite_sequence WHERE name = " + sqlliteral(s.Name) + ";\n")
+		}
+		_, _ = w.WriteString("INSERT INTO sqlite_sequence VALUES(" + sqlliteral(s.Name) + "," + strconv.FormatInt(s.Seq, 10) + ");\n")
+	}
+	return nil
+}
+
+// dumprows 将表中所有行以 INSERT 语句写入 w
+// 生成列不可写入, 有生成列时显式列出其余列.
+func (db *Sqlite) dumprows(tx *sql.Tx, w *bufio.Writer, table string) error {
+	type colinfo struct {
+		Name   string
+		Hidden int
+	}
+	infos, err := scanall[colinfo](db.txquery(tx, "Dump", table, "SELECT name, hidden FROM pragma_table_xinfo(?);", table))
+	if err != nil {
+		return err
+	}
+	cols := make([]string, 0, len(infos))
+	for _, c := range infos {
+		if c.Hidden == 0 {
+			cols = append(cols, quoteident(c.Name))
+		}
+	}
+	collist := ""
+	if len(cols) != len(infos) {
+		collist = "(" + strings.Join(cols, ",") + ")"
+	}
+	rows, err := db.txquery(tx, "Dump", table, "SELECT "+strings.Join(cols, ",")+" FROM "+quoteident(table)+";")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	prefix := "INSERT INTO " + quoteident(table) + collist + " VALUES("
+	for rows.Next() {
+		err = rows.Scan(ptrs...)
+		if err != nil {
+			return err
+		}
+		_, _ = w.WriteString(prefix)
+		for i, v := range vals {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(sqlliteral(v))
+		}
+		_, _ = w.WriteString(");\n")
+	}
+	return rows.Err()
+}
+
+// quoteident 用双引号包裹标识符
+func quoteident(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// sqlliteral 返回 v 的 SQL 字面量表示
+func sqlliteral(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "NULL"
+		case math.IsInf(x, 1):
+			return "1e999"
+		case math.IsInf(x, -1):
+			return "-1e999"
+		}
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		return "X'" + hex.EncodeToString(x) + "'"
+	case string:
+		return "'" + strings.ReplaceAll(x, "'", "''") + "'"
+	case time.Time:
+		return "'" + x.Format("2006-01-02 15:04:05.999999999-07:00") + "'"
+	}
+	return "NULL"
+}
+
+// LoadScript 在一个事务中执行 r 中的多条 SQL 语句, 如 Dump 的输出.
+// 脚本自带的 BEGIN/COMMIT 会被忽略, 任意语句出错时整体回滚.
+// 执行期间关闭外键约束与级联动作, 原本开启时在提交前检查外键.
+// 返回错误.
+func (db *Sqlite) LoadScript(r io.Reader) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	ctx := db.context()
+	conn, err := db.root().db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var fk int
+	err = conn.QueryRowContext(ctx, "PRAGMA foreign_keys;").Scan(&fk)
+	if err != nil {
+		return err
+	}
+	// 事务中设置 foreign_keys 无效, 需在 BEGIN 之前
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF;")
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = "+strconv.Itoa(fk)+";")
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = db.loadscript(tx, r, fk != 0)
+	if err != nil {
+		_ = db.endtx("LoadScript", tx, false)
+		return err
+	}
+	err = db.endtx("LoadScript", tx, true)
+	if err == nil {
+		db.ClearStmtCache()
+	}
+	return err
+}
+
+func (db *Sqlite) loadscript(tx *sql.Tx, r io.Reader, fkcheck bool) error {
+	sc := newscriptscanner(r)
+	for {
+		q, err := sc.next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil && !istxcontrol(q) {
+			_, err = db.execwith("LoadScript", "", q, nil, func(ctx context.Context) (sql.Result, error) {
+				return tx.ExecContext(ctx, q)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !fkcheck {
+		return nil
+	}
+	rows, err := tx.QueryContext(db.context(), "PRAGMA foreign_key_check;")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return errors.New("sqlite: FOREIGN KEY constraint failed")
+	}
+	return rows.Err()
+}
+
+// istxcontrol 判断 q 是否为事务控制语句
+func istxcontrol(q string) bool {
+	f := strings.Fields(strings.ToUpper(strings.TrimSuffix(q, ";")))
+	if len(f) == 0 {
+		return true
+	}
+	switch f[0] {
+	case "BEGIN", "COMMIT", "END", "ROLLBACK":
+		return true
+	}
+	return false
+}
+
+// scriptscanner 将 SQL 脚本切分为单条语句
+type scriptscanner struct {
+	r  *bufio.Reader
+	sb strings.Builder
+}
+
+func newscriptscanner(r io.Reader) *scriptscanner {
+	return &scriptscanner{r: bufio.NewReader(r)}
+}
+
+// next 返回下一条以分号结尾的语句, 忽略引号, 注释与触发器体内的分号.
+// 无更多语句时返回 io.EOF.
+func (s *scriptscanner) next() (string, error) {
+	s.sb.Reset()
+	var quote byte
+	depth := 0 // 触发器中未闭合的 BEGIN 与 CASE 数
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			q := strings.TrimSpace(s.sb.String())
+			if q == "" {
+				return "", io.EOF
+			}
+			return q, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if quote != 0 {
+			s.sb.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if isidentbyte(c) {
+			s.sb.WriteByte(c)
+			continue
+		}
+		depth += blockdelta(s.sb.String())
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '[':
+			quote = ']'
+		case '-', '/':
+			if skipped, err := s.skipcomment(c); err != nil {
+				return "", err
+			} else if skipped {
+				s.sb.WriteByte(' ')
+				continue
+			}
+		case ';':
+			s.sb.WriteByte(c)
+			q := strings.TrimSpace(s.sb.String())
+			if q == ";" {
+				s.sb.Reset()
+				continue
+			}
+			if depth > 0 && istrigger(q) {
+				continue
+			}
+			return q, nil
+		}
+		s.sb.WriteByte(c)
+	}
+}
+
+// skipcomment 在读到 - 或 / 后尝试跳过注释
+func (s *scriptscanner) skipcomment(c byte) (bool, error) {
+	n, err := s.r.Peek(1)
+	if err != nil || (c == '-' && n[0] != '-') || (c == '/' && n[0] != '*') {
+		return false, nil
+	}
+	_, _ = s.r.ReadByte()
+	if c == '-' {
+		_, err = s.r.ReadString('\n')
+		if err == io.EOF {
+			err = nil
+		}
+		return true, err
+	}
+	var prev byte
+	for {
+		b, err := s.r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		if prev == '*' && b == '/' {
+			return true, nil
+		}
+		prev = b
+	}
+}
+
+// istrigger 判断 q 是否为 CREATE TRIGGER 语句
+func istrigger(q string) bool {
+	f := strings.Fields(strings.ToUpper(q))
+	if len(f) < 2 || f[0] != "CREATE" {
+		return false
+	}
+	if f[1] == "TEMP" || f[1] == "TEMPORARY" {
+		return len(f) > 2 && f[2] == "TRIGGER"
+	}
+	return f[1] == "TRIGGER"
+}
+
+// blockdelta 返回 q 末尾的单词对块深度的影响: BEGIN 与 CASE 为 1, END 为 -1.
+// 以 . 限定的列名不计.
+func blockdelta(q string) int {
+	i := len(q)
+	for i > 0 && isidentbyte(q[i-1]) {
+		i--
+	}
+	if i == len(q) || (i > 0 && q[i-1] == '.') {
+		return 0
+	}
+	switch strings.ToUpper(q[i:]) {
+	case "BEGIN", "CASE":
+		return 1
+	case "END":
+		return -1
+	}
+	return 0
+}
+
+func isidentbyte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,129 @@
+package sql
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDumpAndLoad(t *testing.T) {
+	type teacher struct {
+		ID   *int
+		Name string `db:"Name,index"`
+	}
+	type class struct {
+		ID        *int
+		TeacherID int     `db:"TeacherID,references=teacher(ID)"`
+		Note      *string `db:"Note,default='it''s; -- fine'"`
+		Data      []byte
+		Ratio     float64
+		Title     string `db:"Title,generated='class ' || TeacherID"`
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec("PRAGMA foreign_keys = ON;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("teacher", &teacher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("class", &class{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("CREATE TRIGGER touch AFTER INSERT ON class BEGIN UPDATE teacher SET Name = Name || CASE WHEN NEW.Ratio > 1 THEN ';' ELSE ',' END; END;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("CREATE TABLE seq (ID INTEGER PRIMARY KEY AUTOINCREMENT, V TEXT);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("INSERT INTO seq (V) VALUES ('a'), ('b'), ('c'); DELETE FROM seq WHERE ID = 3;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("teacher", &teacher{Name: "O'Neil"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := "a\nb -- c /* d */"
+	err = db.Insert("class", &class{TeacherID: 1, Note: &note, Data: []byte{0, 1, 0xff}, Ratio: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("class", &class{TeacherID: 1, Ratio: math.Inf(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = db.Dump(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := buf.String()
+	if !strings.Contains(script, `INSERT INTO "teacher" VALUES(1,'O''Neil;;');`) {
+		t.Fatal("unexpected dump", script)
+	}
+	if !strings.Contains(script, `VALUES(1,1,'a`+"\n"+`b -- c /* d */',X'0001ff',2.0);`) {
+		t.Fatal("unexpected dump", script)
+	}
+	other := Sqlite{dbpath: filepath.Join(t.TempDir(), "other.db")}
+	err = other.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	err = other.LoadScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf2 bytes.Buffer
+	err = other.Dump(&buf2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf2.String() != script {
+		t.Fatal("dump mismatch\n", buf2.String(), "\n", script)
+	}
+	// AUTOINCREMENT 计数器随脚本恢复
+	r, err := other.Exec("INSERT INTO seq (V) VALUES ('d');")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := r.LastInsertId(); id != 4 {
+		t.Fatal("unexpected rowid", id)
+	}
+	c, err := Find[class](&other, "class", "WHERE ID = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(c.Ratio, 1) || c.Note != nil || c.Title != "class 1" {
+		t.Fatal("unexpected", c)
+	}
+	buf.Reset()
+	err = db.Dump(&buf, "teacher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "class") || strings.Contains(buf.String(), "sqlite_sequence") {
+		t.Fatal("unexpected dump", buf.String())
+	}
+	err = other.LoadScript(strings.NewReader("INSERT INTO teacher (Name) VALUES ('x'); INSERT INTO nosuch VALUES (1);"))
+	if err == nil {
+		t.Fatal("unexpected load")
+	}
+	if other.CanFind("teacher", "WHERE Name = 'x'") {
+		t.Fatal("load not rolled back")
+	}
+}
@@ -0,0 +1,224 @@
+package sql
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPurgeBatch 默认每条语句删除的过期行数
+const DefaultPurgeBatch = 100
+
+// sqlnow 当前的 Unix 秒时间戳, 同一语句中不变
+const sqlnow = "CAST(strftime('%s', 'now') AS INTEGER)"
+
+// expirescolumn 返回 typ 中 db:",expires" 字段的列名, 没有时返回空.
+// 该字段保存 Unix 秒过期时间, 为 0 或 NULL 时永不过期.
+func expirescolumn(typ reflect.Type) string {
+	for _, f := range fieldsof(typ) {
+		if f.expires {
+			return f.name
+		}
+	}
+	return ""
+}
+
+// expired 返回 col 已过期的条件
+func expired(col string) string {
+	col = quoteident(col)
+	return "IFNULL(" + col + ", 0) > 0 AND " + col + " <= " + sqlnow
+}
+
+// selectsource 返回查询 table 中 typ 的行时 FROM 之后的来源.
+// typ 有 db:",expires" 字段时为排除已过期行的同名子查询, 可以表名限定列名,
+// 但子查询没有 rowid, condition 中不能使用 rowid, 需要时应声明 INTEGER PRIMARY KEY 列.
+func (db *Sqlite) selectsource(table string, typ reflect.Type) string {
+	col := expirescolumn(typ)
+	if col == "" {
+		return db.tablename(table)
+	}
+	name := table
+	if i := strings.IndexByte(table, '.'); i > 0 && db.isschema(table[:i]) {
+		name = table[i+1:]
+	}
+	return "(SELECT * FROM " + db.tablename(table) + " WHERE NOT (" + expired(col) + ")) AS " + quoteident(name)
+}
+
+// PurgeExpired 删除 table 中 T 的 db:",expires" 字段已过期的行, 每条语句至多删除 batch 行,
+// batch <= 0 时为 DefaultPurgeBatch. onexpired 非 nil 时对每个删除的行调用.
+// table 不能为 WITHOUT ROWID 表.
+// 返回删除的行数+错误
+func PurgeExpired[T any](db *Sqlite, table string, batch int, onexpired func(*T)) (n int, err error) {
+	col := expirescolumn(reflect.TypeOf((*T)(nil)).Elem())
+	if col == "" {
+		return 0, ErrInvalidTag
+	}
+	if err = db.acquire(); err != nil {
+		return
+	}
+	defer db.release()
+	if batch <= 0 {
+		batch = DefaultPurgeBatch
+	}
+	t := db.tablename(table)
+	q := "DELETE FROM " + t + " WHERE rowid IN (SELECT rowid FROM " + t + " WHERE " + expired(col) +
+		" LIMIT " + strconv.Itoa(batch) + ") RETURNING *;"
+	for {
+		rows, err := db.query("PurgeExpired", table, q)
+		if err != nil {
+			return n, err
+		}
+		m, vals := 0, []*T(nil)
+		for rows.Next() {
+			m++
+			if onexpired == nil {
+				continue
+			}
+			var v T
+			// 行已删除, 无法读取时在本批结束后返回第一个错误
+			if serr := rows.Scan(addrs(&v)...); serr != nil {
+				if err == nil {
+					err = serr
+				}
+				continue
+			}
+			vals = append(vals, &v)
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		_ = rows.Close()
+		n += m
+		// 语句结束后再回调, 以免回调中的写入等待删除的事务
+		for _, v := range vals {
+			onexpired(v)
+		}
+		if err != nil || m < batch {
+			return n, err
+		}
+	}
+}
+
+// Expiry 由 StartJanitor 清理过期行的表, 由 ExpiryOf 生成
+type Expiry struct {
+	Table string
+	purge func(db *Sqlite, batch int) (int, error)
+}
+
+// ExpiryOf 返回清理 table 中 T 的过期行的 Expiry, 见 PurgeExpired
+func ExpiryOf[T any](table string, onexpired func(*T)) Expiry {
+	return Expiry{Table: table, purge: func(db *Sqlite, batch int) (int, error) {
+		return PurgeExpired(db, table, batch, onexpired)
+	}}
+}
+
+// JanitorOptions 后台清理过期行选项
+type JanitorOptions struct {
+	// Interval 清理间隔
+	Interval time.Duration
+	// BatchSize 每条语句删除的行数, 较小时减少对其它操作的阻塞
+	BatchSize int
+	// Tables 要清理的表
+	Tables []Expiry
+	// OnReport 每次清理后回调
+	OnReport func(JanitorReport)
+}
+
+// JanitorReport 一次后台清理的结果
+type JanitorReport struct {
+	At     time.Time
+	Purged map[string]int // 表名 -> 删除的行数
+	Err    error
+}
+
+type janitor struct {
+	opt  JanitorOptions
+	mu   sync.Mutex
+	last JanitorReport
+	loop *loop
+}
+
+// StartJanitor 启动后台清理过期行, 已启动时先停止旧任务.
+// 返回错误.
+func (db *Sqlite) StartJanitor(opt JanitorOptions) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	db = db.root()
+	if opt.Interval <= 0 {
+		return errors.New("sqlite: invalid janitor interval")
+	}
+	l := db.life
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	db.stopjanitor()
+	j := &janitor{opt: opt}
+	j.loop = startloop(opt.Interval, db.ontick(func() { db.purge(j) }))
+	db.janitor = j
+	return nil
+}
+
+// StopJanitor 停止后台清理并等待进行中的清理完成
+func (db *Sqlite) StopJanitor() {
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return
+	}
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	db.stopjanitor()
+}
+
+// stopjanitor 停止后台清理, 需持有 tasks
+func (db *Sqlite) stopjanitor() {
+	j := db.janitor
+	if j == nil {
+		return
+	}
+	j.loop.close()
+	db.janitor = nil
+}
+
+// LastJanitor 返回最近一次后台清理的结果
+func (db *Sqlite) LastJanitor() (r JanitorReport) {
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return
+	}
+	l.tasks.Lock()
+	j := db.janitor
+	l.tasks.Unlock()
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	r = j.last
+	j.mu.Unlock()
+	return
+}
+
+func (db *Sqlite) purge(j *janitor) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	r := JanitorReport{At: time.Now(), Purged: make(map[string]int, len(j.opt.Tables))}
+	for _, e := range j.opt.Tables {
+		n, err := e.purge(db, j.opt.BatchSize)
+		if errors.Is(err, ErrClosed) { // 清理中途被关闭或独占, 跳过本次
+			return
+		}
+		r.Purged[e.Table] += n
+		if err != nil && r.Err == nil {
+			r.Err = err
+		}
+	}
+	j.last = r
+	if j.opt.OnReport != nil {
+		j.opt.OnReport(r)
+	}
+}
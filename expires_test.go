@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpires(t *testing.T) {
+	type code struct {
+		User    int64
+		Code    string
+		Expires int64 `db:"Expires,expires"`
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("code", &code{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	for i := int64(0); i < 25; i++ {
+		err = db.Insert("code", &code{User: i, Code: "old", Expires: now - 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Insert("code", &code{User: 100, Code: "new", Expires: now + 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("code", &code{User: 101, Code: "forever"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 过期的行仍在表中但不会被查到
+	n, err := db.Count("code")
+	if err != nil || n != 27 {
+		t.Fatal(n, err)
+	}
+	_, err = Find[code](&db, "code", "WHERE User = ?", 1)
+	if err != ErrNullResult {
+		t.Fatal(err)
+	}
+	var c code
+	err = db.Find("code", &c, "WHERE User = ?", 100)
+	if err != nil || c.Code != "new" {
+		t.Fatal(c, err)
+	}
+	all, err := FindAll[code](&db, "code", "ORDER BY User")
+	if err != nil || len(all) != 2 || all[1].Code != "forever" {
+		t.Fatal(all, err)
+	}
+
+	if CanFind[code](&db, "code", "WHERE User = ?", 1) || !db.CanFind("code", "WHERE User = ?", 1) {
+		t.Fatal("CanFind[T] should skip expired rows only")
+	}
+	// 可以表名限定列名, 但不能使用 rowid
+	c, err = Find[code](&db, "code", "WHERE code.User = ?", 100)
+	if err != nil || c.Code != "new" {
+		t.Fatal(c, err)
+	}
+	_, err = FindAll[code](&db, "code", "ORDER BY rowid")
+	if err == nil {
+		t.Fatal("rowid is not available on expiring tables")
+	}
+	f := NewTableFamily[code](&db, "code_*")
+	err = f.Insert(1, &code{User: 1, Code: "old", Expires: now - 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Insert(1, &code{User: 2, Code: "new", Expires: now + 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.Union("", "")
+	if err != nil || len(rows) != 1 || rows[0].Row.User != 2 {
+		t.Fatal(rows, err)
+	}
+	// 无法读取删除的行时返回错误, 行仍被删除
+	type badcode struct {
+		User    int64
+		Code    int64
+		Expires int64 `db:"Expires,expires"`
+	}
+	m, err := PurgeExpired(&db, "code_1", 10, func(*badcode) {})
+	if err == nil || m != 1 {
+		t.Fatal(m, err)
+	}
+	if db.CanFind("code_1", "WHERE User = 1") {
+		t.Fatal("expired row not deleted")
+	}
+
+	var expired []int64
+	m, err = PurgeExpired(&db, "code", 10, func(c *code) {
+		expired = append(expired, c.User)
+	})
+	if err != nil || m != 25 || len(expired) != 25 {
+		t.Fatal(m, expired, err)
+	}
+	n, err = db.Count("code")
+	if err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+	_, err = PurgeExpired[struct{ ID int64 }](&db, "code", 10, nil)
+	if err != ErrInvalidTag {
+		t.Fatal(err)
+	}
+
+	err = db.Insert("code", &code{User: 1, Code: "old", Expires: now - 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := make(chan JanitorReport, 16)
+	err = db.StartJanitor(JanitorOptions{
+		Interval: 10 * time.Millisecond,
+		Tables:   []Expiry{ExpiryOf[code]("code", nil)},
+		OnReport: func(r JanitorReport) { reports <- r },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-reports
+	db.StopJanitor()
+	if r.Err != nil || r.Purged["code"] != 1 {
+		t.Fatal(r)
+	}
+	n, err = db.Count("code")
+	if err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+}
@@ -0,0 +1,263 @@
+package sql
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// familychunk UNION ALL 中每条语句至多包含的表数, 低于 SQLITE_MAX_COMPOUND_SELECT
+const familychunk = 100
+
+// maxvariables 每条语句至多绑定的参数数, 即 SQLITE_MAX_VARIABLE_NUMBER
+const maxvariables = 32766
+
+// TableFamily 结构均为 T, 名称由同一模板生成的一组表, 如每个群一张表.
+// 模板中唯一的 * 被替换为键, 如模板 "group_*" 与键 123 对应表 group_123.
+// T 中用 index= 显式命名的索引只会建在第一张表上, 应使用默认的索引名.
+type TableFamily[T any] struct {
+	db         *Sqlite
+	template   string
+	additional []string
+
+	mu      sync.Mutex
+	created map[string]struct{} // 已 Create 的表
+}
+
+// FamilyRow TableFamily 中的一行及其所在的表
+type FamilyRow[T any] struct {
+	Table string
+	Row   *T
+}
+
+// NewTableFamily 创建以 template 为名称模板的 TableFamily,
+// additional 在建表时传给 Create. template 中 * 的个数不为 1 时 panic.
+func NewTableFamily[T any](db *Sqlite, template string, additional ...string) *TableFamily[T] {
+	checktemplate(template)
+	return &TableFamily[T]{
+		db: db, template: template, additional: additional,
+		created: make(map[string]struct{}),
+	}
+}
+
+func checktemplate(template string) {
+	if strings.Count(template, "*") != 1 {
+		panic("sqlite: table template must contain exactly one *")
+	}
+}
+
+// Table 返回键 key 对应的表名
+func (f *TableFamily[T]) Table(key any) string {
+	return strings.Replace(f.template, "*", fmt.Sprint(key), 1)
+}
+
+// Key 返回表 table 的键, table 不属于本组时返回 false
+func (f *TableFamily[T]) Key(table string) (string, bool) {
+	prefix, suffix, _ := strings.Cut(f.template, "*")
+	if len(table) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(table, prefix) || !strings.HasSuffix(table, suffix) {
+		return "", false
+	}
+	return table[len(prefix) : len(table)-len(suffix)], true
+}
+
+// create 在第一次写入 table 前建表
+func (f *TableFamily[T]) create(table string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.created[table]; ok {
+		return nil
+	}
+	err := f.db.Create(table, new(T), f.additional...)
+	if err == nil {
+		f.created[table] = struct{}{}
+	}
+	return err
+}
+
+// forget 使 table 在下次写入时重新建表
+func (f *TableFamily[T]) forget(table string) {
+	f.mu.Lock()
+	delete(f.created, table)
+	f.mu.Unlock()
+}
+
+// Insert 插入 obj 到键 key 对应的表, 表不存在时先建表.
+// 如果 PK 存在会覆盖.
+// 返回错误.
+func (f *TableFamily[T]) Insert(key any, obj *T) error {
+	table := f.Table(key)
+	err := f.create(table)
+	if err != nil {
+		return err
+	}
+	err = f.db.Insert(table, obj)
+	if err != nil && isnosuchtable(err) {
+		// 表已在本组之外被删除, 如 db.Drop, Restore 或 LoadFrom, 重新建表后重试一次
+		f.forget(table)
+		err = f.create(table)
+		if err == nil {
+			err = f.db.Insert(table, obj)
+		}
+	}
+	return err
+}
+
+// isnosuchtable 判断 err 是否为表不存在
+func isnosuchtable(err error) bool {
+	return strings.Contains(err.Error(), "no such table")
+}
+
+// Find 查询键 key 对应的表, 返回第一条结果.
+// condition 可为"WHERE id = 0".
+func (f *TableFamily[T]) Find(key any, condition string, questions ...any) (T, error) {
+	return Find[T](f.db, f.Table(key), condition, questions...)
+}
+
+// FindAll 查询键 key 对应的表, 返回多个结果.
+// condition 可为"WHERE id = 0".
+func (f *TableFamily[T]) FindAll(key any, condition string, questions ...any) ([]*T, error) {
+	return FindAll[T](f.db, f.Table(key), condition, questions...)
+}
+
+// Members 列出键匹配 pattern 的表名, pattern 语法同 path.Match, 为空时列出所有表.
+// 返回所有表名+错误
+func (f *TableFamily[T]) Members(pattern string) (members []string, err error) {
+	tables, err := f.db.ListTables()
+	if err != nil {
+		return
+	}
+	for _, t := range tables {
+		key, ok := f.Key(t)
+		if !ok {
+			continue
+		}
+		if pattern != "" {
+			ok, err = path.Match(pattern, key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			members = append(members, t)
+		}
+	}
+	return
+}
+
+// Drop 删除键匹配 pattern 的表, pattern 同 Members.
+// 返回删除的表数+错误
+func (f *TableFamily[T]) Drop(pattern string) (n int, err error) {
+	members, err := f.Members(pattern)
+	if err != nil {
+		return
+	}
+	return f.droptables(members)
+}
+
+// droptables 删除 tables
+func (f *TableFamily[T]) droptables(tables []string) (n int, err error) {
+	for _, t := range tables {
+		err = f.db.Drop(t)
+		if err != nil {
+			return
+		}
+		f.forget(t)
+		n++
+	}
+	return
+}
+
+// Rename 将键匹配 pattern 的表按 template 以原来的键重命名, 如归档到 "archive_*".
+// template 中 * 的个数不为 1 时 panic.
+// 返回重命名的表数+错误
+func (f *TableFamily[T]) Rename(pattern, template string) (n int, err error) {
+	checktemplate(template)
+	members, err := f.Members(pattern)
+	if err != nil {
+		return
+	}
+	if err = f.db.acquire(); err != nil {
+		return
+	}
+	defer f.db.release()
+	for _, t := range members {
+		key, _ := f.Key(t)
+		q := "ALTER TABLE " + f.db.tablename(t) + " RENAME TO " + wraptable(strings.Replace(template, "*", key, 1)) + ";"
+		_, err = f.db.execdirect("Rename", t, q)
+		if err != nil {
+			return
+		}
+		f.db.invalidatetable(t)
+		f.forget(t)
+		n++
+	}
+	return
+}
+
+// Union 在键匹配 pattern 的所有表中查询, 用 UNION ALL 合并结果并标记所在的表.
+// condition 对每张表生效, 只能为 WHERE 子句, 可为"WHERE id = 0".
+// 默认字段与结构体元素顺序一致.
+// 返回错误.
+func (f *TableFamily[T]) Union(pattern, condition string, questions ...any) ([]FamilyRow[T], error) {
+	members, err := f.Members(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return f.unionof(members, condition, questions)
+}
+
+// unionof 在 tables 中查询, 每条语句至多合并 familychunk 张表
+func (f *TableFamily[T]) unionof(tables []string, condition string, questions []any) ([]FamilyRow[T], error) {
+	err := f.db.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer f.db.release()
+	chunk := familychunk
+	if len(questions) > 0 && maxvariables/len(questions) < chunk {
+		chunk = maxvariables / len(questions)
+	}
+	var vals []FamilyRow[T]
+	for len(tables) > 0 {
+		n := chunk
+		if n > len(tables) {
+			n = len(tables)
+		}
+		vals, err = f.union(vals, tables[:n], condition, questions)
+		if err != nil {
+			return nil, err
+		}
+		tables = tables[n:]
+	}
+	if len(vals) == 0 {
+		return nil, ErrNullResult
+	}
+	return vals, nil
+}
+
+// union 查询 tables 并将结果追加到 vals
+func (f *TableFamily[T]) union(vals []FamilyRow[T], tables []string, condition string, questions []any) ([]FamilyRow[T], error) {
+	selects := make([]string, len(tables))
+	args := make([]any, 0, len(tables)*len(questions))
+	for i, t := range tables {
+		selects[i] = "SELECT " + sqlliteral(t) + ", * FROM " + f.db.selectsource(t, reflect.TypeOf((*T)(nil)).Elem()) + " " + condition
+		args = append(args, questions...)
+	}
+	rows, err := f.db.query("Union", "", strings.Join(selects, " UNION ALL ")+";", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := FamilyRow[T]{Row: new(T)}
+		err = rows.Scan(append([]any{&r.Table}, addrs(r.Row)...)...)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, r)
+	}
+	return vals, rows.Err()
+}
@@ -0,0 +1,105 @@
+package sql
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTableFamily(t *testing.T) {
+	type msg struct {
+		ID   int64
+		Text string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("other", &msg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewTableFamily[msg](&db, "group_*")
+	if f.Table(123) != "group_123" {
+		t.Fatal(f.Table(123))
+	}
+	if k, ok := f.Key("group_123"); !ok || k != "123" {
+		t.Fatal(k, ok)
+	}
+	if _, ok := f.Key("group_"); ok {
+		t.Fatal("empty key")
+	}
+	// 超过 familychunk 张表时分多条语句查询
+	for i := 0; i < familychunk+20; i++ {
+		err = f.Insert(i, &msg{ID: 1, Text: "hello " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Insert(i, &msg{ID: 2, Text: "bye"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 在本组之外删除的表在下次写入时重新建表
+	err = db.Drop("group_7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Insert(7, &msg{ID: 1, Text: "hello 7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := f.Find(7, "WHERE ID = ?", 1)
+	if err != nil || m.Text != "hello 7" {
+		t.Fatal(m, err)
+	}
+	members, err := f.Members("")
+	if err != nil || len(members) != familychunk+20 {
+		t.Fatal(len(members), err)
+	}
+	members, err = f.Members("1?")
+	if err != nil || len(members) != 10 {
+		t.Fatal(members, err)
+	}
+	rows, err := f.Union("", "WHERE ID = ?", 1)
+	if err != nil || len(rows) != familychunk+20 {
+		t.Fatal(len(rows), err)
+	}
+	for _, r := range rows {
+		k, _ := f.Key(r.Table)
+		if r.Row.Text != "hello "+k {
+			t.Fatal(r.Table, r.Row)
+		}
+	}
+
+	n, err := f.Rename("1?", "archive_*")
+	if err != nil || n != 10 {
+		t.Fatal(n, err)
+	}
+	archive := NewTableFamily[msg](&db, "archive_*")
+	all, err := archive.FindAll(12, "")
+	if err != nil || len(all) != 2 {
+		t.Fatal(all, err)
+	}
+	// 重命名后再次写入时重新建表
+	err = f.Insert(12, &msg{ID: 3, Text: "again"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = f.Drop("")
+	if err != nil || n != familychunk+20-10+1 {
+		t.Fatal(n, err)
+	}
+	_, err = f.Union("", "")
+	if err != ErrNullResult {
+		t.Fatal(err)
+	}
+	tables, err := db.ListTables()
+	if err != nil || len(tables) != 11 {
+		t.Fatal(tables, err)
+	}
+}
@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// field 结构体字段与数据库列的映射
+type field struct {
+	index    []int    // 反射下标, 匿名嵌入时为多级
+	name     string   // 列名
+	opts     []string // tag 中列名之后的附加选项
+	readonly bool     // 只读列, 仅查询不写入
+	pk       bool     // 主键列
+	expires  bool     // 过期时间列, 见 expirescolumn
+	indexes  []tagidx // 所属索引
+}
+
+// tagidx 由 tag 声明的索引
+type tagidx struct {
+	name   string // 为空时自动命名
+	unique bool
+	where  string // 部分索引条件
+}
+
+// fieldcache 缓存 reflect.Type -> []field
+var fieldcache sync.Map
+
+// fieldsof 反射 返回结构体类型的所有映射字段.
+// 忽略 db:"-" 与未导出字段, 展开匿名嵌入的结构体.
+func fieldsof(typ reflect.Type) []field {
+	if fs, ok := fieldcache.Load(typ); ok {
+		return fs.([]field)
+	}
+	fs := appendfields(nil, typ, nil)
+	fieldcache.Store(typ, fs)
+	return fs
+}
+
+func appendfields(fs []field, typ reflect.Type, parent []int) []field {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag, hastag := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+		if sf.Anonymous && !hastag && sf.Type.Kind() == reflect.Struct {
+			fs = appendfields(fs, sf.Type, index)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		f := field{index: index}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name, _, _ = strings.Cut(sf.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				name = sf.Name
+			}
+		}
+		f.name = name
+		for _, o := range splitopts(opts) {
+			switch strings.ToLower(o) {
+			case "readonly":
+				f.readonly = true
+				continue
+			case "pk":
+				f.pk = true
+				continue
+			case "expires":
+				f.expires = true
+				continue
+			case "index":
+				f.indexes = append(f.indexes, tagidx{})
+				continue
+			case "unique_index":
+				f.indexes = append(f.indexes, tagidx{unique: true})
+				continue
+			}
+			key, val, ok := cutopt(o)
+			switch {
+			case ok && (key == "index" || key == "unique_index"):
+				idx := tagidx{unique: key == "unique_index"}
+				idx.name, idx.where = cutwhere(val)
+				f.indexes = append(f.indexes, idx)
+				continue
+			case ok && key == "generated":
+				f.readonly = true // 生成列不可写入
+			}
+			f.opts = append(f.opts, o)
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// splitopts 按逗号切分 tag 选项, 忽略括号与引号内的逗号
+func splitopts(s string) (opts []string) {
+	depth, quote, start := 0, byte(0), 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			if o := strings.TrimSpace(s[start:i]); o != "" {
+				opts = append(opts, o)
+			}
+			start = i + 1
+		}
+	}
+	if o := strings.TrimSpace(s[start:]); o != "" {
+		opts = append(opts, o)
+	}
+	return
+}
+
+// cutwhere 切分 "name WHERE expr" 形式的索引声明
+func cutwhere(s string) (name, where string) {
+	name, where, _ = strings.Cut(strings.TrimSpace(s), " ")
+	where = strings.TrimSpace(where)
+	if len(where) >= 5 && strings.EqualFold(where[:5], "WHERE") {
+		where = strings.TrimSpace(where[5:])
+	}
+	return
+}
+
+// writable 返回 objptr 中可写入的列名与值, 跳过只读列.
+// cols 为表中的列, 与结构体字段顺序一致.
+func writable(cols []string, objptr any) ([]string, []any) {
+	fs := fieldsof(reflect.TypeOf(objptr).Elem())
+	vals := values(objptr)
+	wcols := make([]string, 0, len(cols))
+	wvals := make([]any, 0, len(vals))
+	for i, c := range cols {
+		if i < len(fs) && fs[i].readonly {
+			continue
+		}
+		wcols = append(wcols, c)
+		if i < len(vals) {
+			wvals = append(wvals, vals[i])
+		}
+	}
+	return wcols, wvals
+}
@@ -3,7 +3,7 @@ module github.com/FloatTech/sqlite
 go 1.20
 
 require (
-	github.com/FloatTech/ttl v0.0.0-20240716161252-965925764562
+	github.com/klauspost/compress v1.17.9
 	modernc.org/sqlite v1.33.1
 )
 
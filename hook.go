@@ -0,0 +1,222 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// QueryEvent 一条语句的执行信息
+type QueryEvent struct {
+	Op       string // 发起语句的操作, 如 Insert, Find
+	Table    string // 涉及的表, 未知时为空
+	SQL      string
+	Args     []any
+	Start    time.Time
+	Duration time.Duration // 查询语句包括读取结果的时间
+	Rows     int64         // 影响或读取的行数
+	Err      error
+
+	ctx  context.Context
+	span Span
+}
+
+// Logger 接收每条语句的执行信息
+type Logger interface {
+	LogQuery(ctx context.Context, e *QueryEvent)
+}
+
+// LoggerFunc 将函数用作 Logger
+type LoggerFunc func(ctx context.Context, e *QueryEvent)
+
+// LogQuery 调用 f
+func (f LoggerFunc) LogQuery(ctx context.Context, e *QueryEvent) {
+	f(ctx, e)
+}
+
+// LogOptions 语句日志选项
+type LogOptions struct {
+	// NoArgs 不记录参数
+	NoArgs bool
+	// Redact 非 nil 时记录其返回值代替第 i 个参数, 如隐藏密码
+	Redact func(i int, arg any) any
+	// SlowThreshold 大于 0 时只记录耗时不低于该值或出错的语句
+	SlowThreshold time.Duration
+}
+
+// hooks 语句执行的观察者
+type hooks struct {
+	logger  Logger
+	logopt  LogOptions
+	metrics Metrics
+	tracer  Tracer
+}
+
+// WithLogger 将每条语句的执行信息交给 l, opt 可为 nil.
+func WithLogger(l Logger, opt *LogOptions) Option {
+	return func(db *Sqlite) {
+		db.hooks.logger = l
+		db.hooks.logopt = LogOptions{}
+		if opt != nil {
+			db.hooks.logopt = *opt
+		}
+	}
+}
+
+// start 开始一条语句, 有 Tracer 时开启 span.
+// 返回执行语句使用的 ctx.
+func (db *Sqlite) start(e *QueryEvent) context.Context {
+	e.Start = time.Now()
+	e.ctx = db.context()
+	if t := db.root().hooks.tracer; t != nil {
+		e.ctx, e.span = t.Start(e.ctx, e)
+	}
+	return e.ctx
+}
+
+// observe 上报一条已结束的语句
+func (db *Sqlite) observe(e *QueryEvent) {
+	e.Duration = time.Since(e.Start)
+	h := &db.root().hooks
+	if e.span != nil {
+		e.span.End(e)
+	}
+	if h.metrics != nil {
+		h.metrics.ObserveQuery(e.Op, e.Table, e.Duration, e.Err)
+	}
+	if h.logger == nil {
+		return
+	}
+	if h.logopt.SlowThreshold > 0 && e.Duration < h.logopt.SlowThreshold && e.Err == nil {
+		return
+	}
+	le := *e
+	switch {
+	case h.logopt.NoArgs:
+		le.Args = nil
+	case h.logopt.Redact != nil && len(e.Args) > 0:
+		le.Args = make([]any, len(e.Args))
+		for i, a := range e.Args {
+			le.Args[i] = h.logopt.Redact(i, a)
+		}
+	}
+	h.logger.LogQuery(e.ctx, &le)
+}
+
+// exec 编译并执行 q, 上报执行信息
+func (db *Sqlite) exec(op, table, q string, args ...any) (sql.Result, error) {
+	return db.execwith(op, table, q, args, func(ctx context.Context) (sql.Result, error) {
+		stmt, err := db.compile(q)
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.release()
+		return retry(db, op, func() (sql.Result, error) {
+			return stmt.stmt.ExecContext(ctx, args...)
+		})
+	})
+}
+
+// execdirect 不经语句缓存执行 q, 上报执行信息
+func (db *Sqlite) execdirect(op, table, q string, args ...any) (sql.Result, error) {
+	return db.execwith(op, table, q, args, func(ctx context.Context) (sql.Result, error) {
+		return retry(db, op, func() (sql.Result, error) {
+			return db.root().db.ExecContext(ctx, q, args...)
+		})
+	})
+}
+
+// execwith 用 f 执行 q, 上报执行信息
+func (db *Sqlite) execwith(op, table, q string, args []any, f func(ctx context.Context) (sql.Result, error)) (sql.Result, error) {
+	e := QueryEvent{Op: op, Table: table, SQL: q, Args: args}
+	r, err := f(db.start(&e))
+	if err == nil {
+		e.Rows, _ = r.RowsAffected()
+	}
+	e.Err = err
+	db.observe(&e)
+	return r, err
+}
+
+// rows 关闭时上报执行信息的 *sql.Rows
+type rows struct {
+	*sql.Rows
+	db     *Sqlite
+	e      QueryEvent
+	closed bool
+}
+
+// query 编译并执行 q, 在结果关闭时上报执行信息
+func (db *Sqlite) query(op, table, q string, args ...any) (*rows, error) {
+	return db.querywith(op, table, q, args, func(ctx context.Context) (*sql.Rows, error) {
+		stmt, err := db.compile(q)
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.release()
+		return retry(db, op, func() (*sql.Rows, error) {
+			return stmt.stmt.QueryContext(ctx, args...)
+		})
+	})
+}
+
+// querywith 用 f 执行 q, 在结果关闭时上报执行信息
+func (db *Sqlite) querywith(op, table, q string, args []any, f func(ctx context.Context) (*sql.Rows, error)) (*rows, error) {
+	r := &rows{db: db, e: QueryEvent{Op: op, Table: table, SQL: q, Args: args}}
+	var err error
+	r.Rows, err = f(db.start(&r.e))
+	if err != nil {
+		r.e.Err = err
+		db.observe(&r.e)
+		return nil, err
+	}
+	return r, nil
+}
+
+// scanrow 执行 q 并将第一行写入 dest
+func (db *Sqlite) scanrow(op, table, q string, dest ...any) error {
+	r, err := db.querywith(op, table, q, nil, func(ctx context.Context) (*sql.Rows, error) {
+		return retry(db, op, func() (*sql.Rows, error) {
+			return db.root().db.QueryContext(ctx, q)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if !r.Next() {
+		if err = r.Err(); err != nil {
+			return err
+		}
+		return ErrNullResult
+	}
+	return r.Scan(dest...)
+}
+
+func (r *rows) Next() bool {
+	if r.Rows.Next() {
+		r.e.Rows++
+		return true
+	}
+	return false
+}
+
+func (r *rows) Scan(dest ...any) error {
+	err := r.Rows.Scan(dest...)
+	if err != nil && r.e.Err == nil {
+		r.e.Err = err
+	}
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		if r.e.Err == nil {
+			r.e.Err = r.Rows.Err()
+		}
+		r.db.observe(&r.e)
+	}
+	return err
+}
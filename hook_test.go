@@ -0,0 +1,72 @@
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	type user struct {
+		ID       int64
+		Name     string
+		Password string
+	}
+	var events []QueryEvent
+	l := LoggerFunc(func(_ context.Context, e *QueryEvent) {
+		events = append(events, *e)
+	})
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := New(dbpath, WithLogger(l, &LogOptions{
+		Redact: func(i int, arg any) any {
+			if i == 2 {
+				return "***"
+			}
+			return arg
+		},
+	}))
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("user", &user{ID: 1, Name: "Anna", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FindAll[user](&db, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var insert, findall *QueryEvent
+	for i := range events {
+		switch {
+		case events[i].Op == "Insert" && events[i].Args != nil:
+			insert = &events[i]
+		case events[i].Op == "FindAll":
+			findall = &events[i]
+		}
+	}
+	if insert == nil || findall == nil {
+		t.Fatal("missing events", events)
+	}
+	if insert.Table != "user" || insert.Rows != 1 || insert.Args[2] != "***" || insert.Args[1] != "Anna" {
+		t.Fatal("unexpected insert event", insert)
+	}
+	if findall.Rows != 1 || findall.Err != nil || findall.Duration <= 0 {
+		t.Fatal("unexpected find event", findall)
+	}
+
+	events = nil
+	WithLogger(l, &LogOptions{SlowThreshold: time.Hour})(&db)
+	_ = db.Find("user", &user{}, "WHERE ID = 1")
+	_ = db.Find("nosuchtable", &user{}, "")
+	if len(events) != 1 || events[0].Err == nil || events[0].Table != "nosuchtable" {
+		t.Fatal("slow threshold should only keep failed statements", events)
+	}
+}
@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"errors"
+	"strings"
+)
+
+// Index 索引定义
+type Index struct {
+	Name    string
+	Table   string
+	Columns []string
+	Unique  bool
+	Where   string // 部分索引条件, 为空时为普通索引
+}
+
+// tagindexes 汇总字段 tag 中声明的索引.
+// 同名索引按字段顺序组合为多列索引.
+func tagindexes(table string, fs []field) (idxs []Index) {
+	pos := map[string]int{}
+	for _, f := range fs {
+		for _, ti := range f.indexes {
+			name := ti.name
+			if name == "" {
+				name = "idx_" + table + "_" + f.name
+			}
+			i, ok := pos[name]
+			if !ok {
+				pos[name] = len(idxs)
+				idxs = append(idxs, Index{Name: name, Table: table})
+				i = len(idxs) - 1
+			}
+			idxs[i].Columns = append(idxs[i].Columns, f.name)
+			idxs[i].Unique = idxs[i].Unique || ti.unique
+			if idxs[i].Where == "" {
+				idxs[i].Where = ti.where
+			}
+		}
+	}
+	return
+}
+
+// CreateIndex 建立索引, 已存在时忽略.
+// 返回错误.
+func (db *Sqlite) CreateIndex(idx Index) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	if idx.Name == "" || idx.Table == "" || len(idx.Columns) == 0 {
+		return errors.New("sqlite: invalid index definition")
+	}
+	q := "CREATE INDEX IF NOT EXISTS "
+	if idx.Unique {
+		q = "CREATE UNIQUE INDEX IF NOT EXISTS "
+	}
+	q += wraptable(idx.Name) + " ON " + wraptable(idx.Table) + " (" + strings.Join(idx.Columns, ", ") + ")"
+	if idx.Where != "" {
+		q += " WHERE " + idx.Where
+	}
+	_, err := db.exec("CreateIndex", idx.Table, q+";")
+	return err
+}
+
+// DropIndex 删除索引, 不存在时忽略.
+// 返回错误.
+func (db *Sqlite) DropIndex(name string) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	_, err := db.exec("DropIndex", "", "DROP INDEX IF EXISTS "+wraptable(name)+";")
+	return err
+}
+
+// ListIndexes 列出 table 上的所有索引, 包括主键与 UNIQUE 约束自动生成的索引.
+// 返回索引+错误.
+func (db *Sqlite) ListIndexes(table string) (idxs []Index, err error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	type idxrow struct {
+		Name    string
+		Unique  bool
+		Partial bool
+		SQL     *string
+	}
+	rows, err := queryall[idxrow](db, "ListIndexes", table,
+		"SELECT l.name, l.\"unique\", l.partial, m.sql FROM pragma_index_list(?) AS l "+
+			"LEFT JOIN sqlite_master AS m ON m.type = 'index' AND m.name = l.name ORDER BY l.name;", table)
+	if err == ErrNullResult {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	idxs = make([]Index, len(rows))
+	for i, r := range rows {
+		idxs[i] = Index{Name: r.Name, Table: table, Unique: r.Unique}
+		idxs[i].Columns, err = db.indexcolumns(r.Name)
+		if err != nil {
+			return nil, err
+		}
+		if r.Partial && r.SQL != nil {
+			idxs[i].Where = partialwhere(*r.SQL)
+		}
+	}
+	return
+}
+
+// indexcolumns 按顺序返回索引包含的列名
+func (db *Sqlite) indexcolumns(name string) ([]string, error) {
+	type colrow struct {
+		Name *string
+	}
+	rows, err := queryall[colrow](db, "ListIndexes", "", "SELECT name FROM pragma_index_info(?) ORDER BY seqno;", name)
+	if err == ErrNullResult {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]string, 0, len(rows))
+	for _, r := range rows {
+		if r.Name != nil { // 表达式索引的列名为 NULL
+			cols = append(cols, *r.Name)
+		}
+	}
+	return cols, nil
+}
+
+// partialwhere 从 CREATE INDEX 语句中提取 WHERE 条件
+func partialwhere(q string) string {
+	start := strings.IndexByte(q, '(')
+	if start < 0 {
+		return ""
+	}
+	end, depth := start, 0
+	for ; end < len(q); end++ {
+		if q[end] == '(' {
+			depth++
+		} else if q[end] == ')' {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	if end >= len(q) {
+		return ""
+	}
+	rest := strings.TrimSpace(q[end+1:])
+	if len(rest) < 5 || !strings.EqualFold(rest[:5], "WHERE") {
+		return ""
+	}
+	return strings.TrimSpace(rest[5:])
+}
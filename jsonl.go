@@ -0,0 +1,82 @@
+package sql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// ExportJSONL 查询数据库, 将每行结果按 T 的 json 编码写为一行.
+// condition 可为"WHERE id = 0".
+// 返回写入的行数+错误.
+func ExportJSONL[T any](db *Sqlite, table string, condition string, w io.Writer, questions ...any) (n int, err error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var obj T
+	err = db.FindFor(table, &obj, condition, func() error {
+		err := enc.Encode(&obj)
+		if err == nil {
+			n++
+		}
+		return err
+	}, questions...)
+	if err == ErrNullResult {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	return n, bw.Flush()
+}
+
+// JSONLOptions ImportJSONL 的选项
+type JSONLOptions struct {
+	// BatchSize 每个事务的行数, 默认为 DefaultBatchSize
+	BatchSize int
+}
+
+// ImportJSONL 从 r 逐行读取 T 的 json 编码并插入 table, 空行会被忽略.
+// 可选的 opt 见 JSONLOptions.
+// 出错时中止并返回 *RowError, 已提交的批次不会回滚.
+// 返回成功插入的行数+错误.
+func ImportJSONL[T any](db *Sqlite, table string, r io.Reader, opt ...*JSONLOptions) (int, error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	size := 0
+	if len(opt) > 0 && opt[0] != nil {
+		size = opt[0].BatchSize
+	}
+	br := bufio.NewReader(r)
+	b := &batchinserter{db: db, op: "ImportJSONL", table: table, size: batchsize(size)}
+	defer b.rollback()
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return b.n, err
+		}
+		eof := err == io.EOF
+		data = bytes.TrimSpace(data)
+		if len(data) > 0 {
+			var obj T
+			err = json.Unmarshal(data, &obj)
+			if err == nil {
+				err = b.insert(&obj)
+			}
+			if err != nil {
+				return b.n, &RowError{Line: line, Err: err}
+			}
+		}
+		if eof {
+			break
+		}
+	}
+	err := b.commit()
+	return b.n, err
+}
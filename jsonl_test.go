@@ -0,0 +1,59 @@
+package sql
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONL(t *testing.T) {
+	type user struct {
+		ID   *int   `json:"id"`
+		Name string `json:"name"`
+		Tags []byte `json:"tags,omitempty"`
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{"id":1,"name":"Anna","tags":"AQI="}` + "\n\n" + `{"id":2,"name":"Bob"}`
+	n, err := ImportJSONL[user](&db, "user", strings.NewReader(input), &JSONLOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatal("unexpected", n)
+	}
+	var buf bytes.Buffer
+	n, err = ExportJSONL[user](&db, "user", "ORDER BY id", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"id":1,"name":"Anna","tags":"AQI="}` + "\n" + `{"id":2,"name":"Bob"}` + "\n"
+	if n != 2 || buf.String() != expected {
+		t.Fatal("unexpected", n, buf.String())
+	}
+	buf.Reset()
+	n, err = ExportJSONL[user](&db, "user", "WHERE id > ?", &buf, 5)
+	if err != nil || n != 0 || buf.Len() != 0 {
+		t.Fatal("unexpected", n, err)
+	}
+	_, err = ImportJSONL[user](&db, "user", strings.NewReader(`{"id":3,"name":"Cat"}`+"\n"+`{"id":"x"}`))
+	var rerr *RowError
+	if !errors.As(err, &rerr) || rerr.Line != 2 {
+		t.Fatal("unexpected", err)
+	}
+	if db.CanFind("user", "WHERE id = 3") {
+		t.Fatal("unexpected row")
+	}
+}
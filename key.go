@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"reflect"
+	"strings"
+)
+
+// WithoutRowID 作为 Create 的 additional 参数时建立 WITHOUT ROWID 表
+const WithoutRowID = "WITHOUT ROWID"
+
+// primarykeys 返回主键字段的下标.
+// 未声明 db:",pk" 时默认第一个字段为主键, 此时 implicit 为 true.
+func primarykeys(fs []field) (pks []int, implicit bool) {
+	for i, f := range fs {
+		if f.pk {
+			pks = append(pks, i)
+		}
+	}
+	if len(pks) == 0 && len(fs) > 0 {
+		return []int{0}, true
+	}
+	return
+}
+
+// keycond 返回按 objptr 主键查询的条件与参数
+func keycond(objptr any) (string, []any) {
+	fs := fieldsof(reflect.TypeOf(objptr).Elem())
+	pks, _ := primarykeys(fs)
+	vals := values(objptr)
+	keys := make([]any, len(pks))
+	for i, k := range pks {
+		keys[i] = vals[k]
+	}
+	return keycondof(fs, pks), keys
+}
+
+// keycondof 返回 "WHERE pk1 = ? AND pk2 = ?"
+func keycondof(fs []field, pks []int) string {
+	sb := strings.Builder{}
+	sb.WriteString("WHERE ")
+	for i, k := range pks {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(fs[k].name)
+		sb.WriteString(" = ?")
+	}
+	return sb.String()
+}
+
+// Get 按 objptr 中的主键查询, 写入结果到 objptr.
+// 返回错误.
+func (db *Sqlite) Get(table string, objptr any) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	cond, keys := keycond(objptr)
+	return db.Find(table, objptr, cond, keys...)
+}
+
+// Get 按主键查询, 返回结果.
+// keys 与结构体主键字段顺序一致.
+// 返回错误.
+func Get[T any](db *Sqlite, table string, keys ...any) (obj T, err error) {
+	if err = db.acquire(); err != nil {
+		return
+	}
+	defer db.release()
+	fs := fieldsof(reflect.TypeOf(&obj).Elem())
+	pks, _ := primarykeys(fs)
+	if len(keys) != len(pks) {
+		err = ErrKeyCount
+		return
+	}
+	return Find[T](db, table, keycondof(fs, pks), keys...)
+}
+
+// Update 按 objptr 中的主键更新其余可写列.
+// 无对应行时返回 ErrNullResult.
+// 返回错误.
+func (db *Sqlite) Update(table string, objptr any) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	fs := fieldsof(reflect.TypeOf(objptr).Elem())
+	pks, _ := primarykeys(fs)
+	vals := values(objptr)
+	sets := make([]string, 0, len(fs))
+	args := make([]any, 0, len(fs))
+	for i, f := range fs {
+		if f.readonly || isin(i, pks) {
+			continue
+		}
+		sets = append(sets, f.name+" = ?")
+		args = append(args, vals[i])
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	for _, k := range pks {
+		args = append(args, vals[k])
+	}
+	q := "UPDATE " + db.tablename(table) + " SET " + strings.Join(sets, ", ") + " " + keycondof(fs, pks) + ";"
+	r, err := db.exec("Update", table, q, args...)
+	if err != nil {
+		return err
+	}
+	n, err := r.RowsAffected()
+	if err == nil && n == 0 {
+		err = ErrNullResult
+	}
+	return err
+}
+
+// DelByKey 按 objptr 中的主键删除数据库表项.
+// 返回错误.
+func (db *Sqlite) DelByKey(table string, objptr any) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	cond, keys := keycond(objptr)
+	return db.Del(table, cond, keys...)
+}
+
+func isin(i int, s []int) bool {
+	for _, x := range s {
+		if x == i {
+			return true
+		}
+	}
+	return false
+}
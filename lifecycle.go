@@ -0,0 +1,152 @@
+package sql
+
+import "sync"
+
+// lifestate 数据库的生命周期状态
+type lifestate int
+
+const (
+	stateclosed lifestate = iota
+	stateopening
+	stateopen
+	stateclosing
+)
+
+// lifecycle 数据库的生命周期, 由 db 及其 WithContext 视图共享.
+// 操作在 open 状态下 acquire, 结束时 release, Close 等待所有操作 release.
+type lifecycle struct {
+	mu       sync.Mutex
+	cond     *sync.Cond // 状态或 inflight 变化时广播
+	state    lifestate
+	inflight int  // 进行中的操作数
+	opened   bool // 是否曾经打开
+
+	tasks sync.Mutex // 保护 db 的 snapshotter, maintainer 与 janitor
+}
+
+func newlifecycle() *lifecycle {
+	l := &lifecycle{}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire 开始一个操作, 数据库未打开时返回 ErrNilDB, 已关闭时返回 ErrClosed.
+// 有 WithLazyOpen 选项且从未打开时先打开数据库.
+func (db *Sqlite) acquire() error {
+	if err := db.checkref(); err != nil {
+		return err
+	}
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return ErrNilDB
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.state == stateopening {
+		l.cond.Wait()
+	}
+	if l.state == stateclosed && !l.opened && db.lazy {
+		l.mu.Unlock()
+		err := db.Open(db.lazyttl)
+		l.mu.Lock()
+		if err != nil {
+			return err
+		}
+		for l.state == stateopening {
+			l.cond.Wait()
+		}
+	}
+	if l.state != stateopen {
+		return l.closederr()
+	}
+	l.inflight++
+	return nil
+}
+
+// checkref 已 Close 的 Shared 句柄返回 ErrClosed.
+// 需在 db.root() 之前调用, root 返回的 db 不再指向句柄.
+func (db *Sqlite) checkref() error {
+	if db.share != nil && db.share.released.Load() {
+		return ErrClosed
+	}
+	return nil
+}
+
+// closederr 返回未打开时操作的错误, 需持有 mu
+func (l *lifecycle) closederr() error {
+	if l.opened {
+		return ErrClosed
+	}
+	return ErrNilDB
+}
+
+// release 结束 acquire 开始的操作
+func (db *Sqlite) release() {
+	l := db.root().life
+	l.mu.Lock()
+	l.inflight--
+	if l.inflight == 0 {
+		l.cond.Broadcast()
+	}
+	l.mu.Unlock()
+}
+
+// transition 等待 opening 与 closing 结束后, 若状态为 from 则切换到 to.
+// 返回切换前的状态.
+func (l *lifecycle) transition(from, to lifestate) lifestate {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.state == stateopening || l.state == stateclosing {
+		l.cond.Wait()
+	}
+	s := l.state
+	if s == from {
+		l.state = to
+	}
+	return s
+}
+
+// settle 结束 opening 或 closing, 切换到 open 或 closed
+func (l *lifecycle) settle(open bool) {
+	l.mu.Lock()
+	if open {
+		l.state = stateopen
+		l.opened = true
+	} else {
+		l.state = stateclosed
+	}
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+// drain 在 closing 状态下等待所有操作结束
+func (l *lifecycle) drain() {
+	l.mu.Lock()
+	for l.inflight > 0 {
+		l.cond.Wait()
+	}
+	l.mu.Unlock()
+}
+
+// exclusive 等待进行中的操作结束后独占地执行 f, 期间新的操作返回 ErrClosed.
+// f 结束后 db 仍打开时回到 open 状态.
+func (db *Sqlite) exclusive(f func() error) error {
+	if err := db.checkref(); err != nil {
+		return err
+	}
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return ErrNilDB
+	}
+	if l.transition(stateopen, stateclosing) != stateopen {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.closederr()
+	}
+	l.drain()
+	err := f()
+	l.settle(db.db != nil)
+	return err
+}
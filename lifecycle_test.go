@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := New(dbpath)
+	if err := db.Insert("user", &user{}); err != ErrNilDB {
+		t.Fatal("expected ErrNilDB before Open, got", err)
+	}
+	if _, err := db.Exec("PRAGMA user_version;"); err != ErrNilDB {
+		t.Fatal("expected ErrNilDB from Exec before Open, got", err)
+	}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Open(time.Minute)
+	if err != nil || db.cachettl != time.Hour {
+		t.Fatal("second Open should be a no-op", err, db.cachettl)
+	}
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 3; i++ {
+		err = db.Insert("user", &user{ID: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close 等待进行中的 FindFor 结束
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	var (
+		once  sync.Once
+		found int
+	)
+	go func() {
+		var u user
+		result <- db.FindFor("user", &u, "", func() error {
+			once.Do(func() { close(started) })
+			time.Sleep(20 * time.Millisecond)
+			found++
+			return nil
+		})
+	}()
+	<-started
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				err := db.Insert("user", &user{ID: 100})
+				if err != nil && err != ErrClosed {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 3 {
+		t.Fatal("Close returned before in-flight operation finished", found)
+	}
+	wg.Wait()
+	if err = <-result; err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatal("second Close should be a no-op", err)
+	}
+	if _, err = db.Count("user"); !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	if _, err = db.Exec("PRAGMA user_version;"); !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed from Exec, got", err)
+	}
+	err = db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Count("user"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,46 @@
+package sql
+
+import "time"
+
+// loop 后台定时任务
+type loop struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startloop 每隔 interval 在后台调用一次 f
+func startloop(interval time.Duration, f func()) *loop {
+	l := &loop{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-t.C:
+				f()
+			}
+		}
+	}()
+	return l
+}
+
+// ontick 返回定时任务每次调用的函数, 在 acquire 期间执行 f.
+// db 已关闭或正被 Restore 等独占时跳过本次.
+func (db *Sqlite) ontick(f func()) func() {
+	return func() {
+		if db.acquire() != nil {
+			return
+		}
+		defer db.release()
+		f()
+	}
+}
+
+// close 停止任务并等待进行中的 f 返回
+func (l *loop) close() {
+	close(l.stop)
+	<-l.done
+}
@@ -0,0 +1,309 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckpointMode WAL 检查点模式
+type CheckpointMode string
+
+const (
+	CheckpointPassive  CheckpointMode = "PASSIVE"
+	CheckpointFull     CheckpointMode = "FULL"
+	CheckpointRestart  CheckpointMode = "RESTART"
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+// CheckpointResult WAL 检查点结果
+type CheckpointResult struct {
+	Busy         bool // 是否因锁而未能完成
+	Log          int  // WAL 中的页数
+	Checkpointed int  // 已写回数据库的页数
+}
+
+// pagestat 返回页大小, 总页数与空闲页数
+func (db *Sqlite) pagestat(op string) (size, count, free int64, err error) {
+	err = db.scanrow(op, "", "SELECT page_size, page_count, freelist_count FROM pragma_page_size, pragma_page_count, pragma_freelist_count;", &size, &count, &free)
+	return
+}
+
+// Vacuum 重建数据库文件以回收空闲页.
+// 返回回收的字节数+错误.
+func (db *Sqlite) Vacuum() (int64, error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	return db.reclaim("Vacuum", "VACUUM;")
+}
+
+// IncrementalVacuum 回收至多 pages 个空闲页, pages <= 0 时回收全部.
+// 仅在 PRAGMA auto_vacuum = INCREMENTAL 时有效.
+// 返回回收的字节数+错误.
+func (db *Sqlite) IncrementalVacuum(pages int) (int64, error) {
+	if err := db.acquire(); err != nil {
+		return 0, err
+	}
+	defer db.release()
+	q := "PRAGMA incremental_vacuum;"
+	if pages > 0 {
+		q = "PRAGMA incremental_vacuum(" + strconv.Itoa(pages) + ");"
+	}
+	return db.reclaim("IncrementalVacuum", q)
+}
+
+// reclaim 执行 q 并返回前后文件页数之差对应的字节数
+func (db *Sqlite) reclaim(op, q string) (int64, error) {
+	size, before, _, err := db.pagestat(op)
+	if err != nil {
+		return 0, err
+	}
+	// incremental_vacuum 每步返回一行, 需读完才会执行完毕
+	rows, err := db.querywith(op, "", q, nil, func(ctx context.Context) (*sql.Rows, error) {
+		return db.root().db.QueryContext(ctx, q)
+	})
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	_, after, _, err := db.pagestat(op)
+	if err != nil {
+		return 0, err
+	}
+	return (before - after) * size, nil
+}
+
+// Analyze 收集表与索引的统计信息供查询优化器使用.
+// tables 为空时分析整个数据库.
+func (db *Sqlite) Analyze(tables ...string) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	if len(tables) == 0 {
+		_, err := db.execdirect("Analyze", "", "ANALYZE;")
+		return err
+	}
+	for _, t := range tables {
+		_, err := db.execdirect("Analyze", t, "ANALYZE "+db.tablename(t)+";")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Optimize 执行 PRAGMA optimize, 仅在必要时更新统计信息.
+func (db *Sqlite) Optimize() error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	_, err := db.execdirect("Optimize", "", "PRAGMA optimize;")
+	return err
+}
+
+// WALCheckpoint 将 WAL 中的内容写回数据库.
+// 非 WAL 模式下返回的 Log 与 Checkpointed 均为 -1.
+func (db *Sqlite) WALCheckpoint(mode CheckpointMode) (r CheckpointResult, err error) {
+	if err := db.acquire(); err != nil {
+		return r, err
+	}
+	defer db.release()
+	switch mode {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+	default:
+		return r, errors.New("sqlite: invalid checkpoint mode " + string(mode))
+	}
+	var busy int
+	err = db.scanrow("WALCheckpoint", "", "PRAGMA wal_checkpoint("+string(mode)+");", &busy, &r.Log, &r.Checkpointed)
+	r.Busy = busy != 0
+	return
+}
+
+// walsize 返回 WAL 文件的字节数, 不存在时为 0
+func (db *Sqlite) walsize() int64 {
+	fi, err := os.Stat(db.root().dbpath + "-wal")
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// MaintenanceOptions 后台维护选项
+type MaintenanceOptions struct {
+	// Interval 检查间隔
+	Interval time.Duration
+	// Optimize 每次检查时执行 Optimize
+	Optimize bool
+	// FreelistBytes 空闲页超过该字节数时回收, 0 为不回收.
+	// auto_vacuum = INCREMENTAL 时使用 IncrementalVacuum, 否则使用 Vacuum.
+	FreelistBytes int64
+	// WALBytes WAL 文件超过该字节数时执行 TRUNCATE 检查点, 0 为不检查
+	WALBytes int64
+	// OnReport 每次检查后回调
+	OnReport func(MaintenanceReport)
+}
+
+// MaintenanceReport 一次后台维护的结果
+type MaintenanceReport struct {
+	At        time.Time
+	Ran       []string // 执行了的操作
+	Reclaimed int64    // 回收的数据库文件字节数
+	WALBytes  int64    // 检查点前后 WAL 文件减少的字节数
+	Err       error
+}
+
+type maintainer struct {
+	opt  MaintenanceOptions
+	mu   sync.Mutex
+	last MaintenanceReport
+	loop *loop
+}
+
+// StartMaintenance 启动后台维护, 已启动时先停止旧任务.
+// 返回错误.
+func (db *Sqlite) StartMaintenance(opt MaintenanceOptions) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	db = db.root()
+	if opt.Interval <= 0 {
+		return errors.New("sqlite: invalid maintenance interval")
+	}
+	l := db.life
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	db.stopmaintenance()
+	m := &maintainer{opt: opt}
+	m.loop = startloop(opt.Interval, db.ontick(func() { db.maintain(m) }))
+	db.maintainer = m
+	return nil
+}
+
+// StopMaintenance 停止后台维护并等待进行中的维护完成
+func (db *Sqlite) StopMaintenance() {
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return
+	}
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	db.stopmaintenance()
+}
+
+// stopmaintenance 停止后台维护, 需持有 tasks
+func (db *Sqlite) stopmaintenance() {
+	m := db.maintainer
+	if m == nil {
+		return
+	}
+	m.loop.close()
+	db.maintainer = nil
+}
+
+// LastMaintenance 返回最近一次后台维护的结果
+func (db *Sqlite) LastMaintenance() (r MaintenanceReport) {
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return
+	}
+	l.tasks.Lock()
+	m := db.maintainer
+	l.tasks.Unlock()
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	r = m.last
+	m.mu.Unlock()
+	return
+}
+
+func (db *Sqlite) maintain(m *maintainer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := MaintenanceReport{At: time.Now()}
+	defer func() {
+		if errors.Is(r.Err, ErrClosed) { // 维护中途被关闭或独占, 跳过本次
+			return
+		}
+		m.last = r
+		if m.opt.OnReport != nil {
+			m.opt.OnReport(r)
+		}
+	}()
+	if m.opt.Optimize {
+		r.Err = db.Optimize()
+		if r.Err != nil {
+			return
+		}
+		r.Ran = append(r.Ran, "optimize")
+	}
+	if m.opt.FreelistBytes > 0 {
+		size, _, free, err := db.pagestat("maintain")
+		if err != nil {
+			r.Err = err
+			return
+		}
+		if free*size >= m.opt.FreelistBytes {
+			var mode int
+			r.Err = db.scanrow("maintain", "", "PRAGMA auto_vacuum;", &mode)
+			if r.Err != nil {
+				return
+			}
+			var n int64
+			if mode == 2 { // INCREMENTAL
+				n, r.Err = db.IncrementalVacuum(0)
+				r.Ran = append(r.Ran, "incremental_vacuum")
+			} else {
+				n, r.Err = db.Vacuum()
+				r.Ran = append(r.Ran, "vacuum")
+			}
+			r.Reclaimed += n
+			if r.Err != nil {
+				return
+			}
+		}
+	}
+	if m.opt.WALBytes > 0 {
+		before := db.walsize()
+		if before >= m.opt.WALBytes {
+			_, r.Err = db.WALCheckpoint(CheckpointTruncate)
+			r.Ran = append(r.Ran, "wal_checkpoint")
+			r.WALBytes = before - db.walsize()
+		}
+	}
+}
+
+// String 返回维护结果的摘要
+func (r MaintenanceReport) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(r.At.Format(time.RFC3339))
+	sb.WriteString(" ran [")
+	sb.WriteString(strings.Join(r.Ran, ", "))
+	sb.WriteString("] reclaimed ")
+	sb.WriteString(strconv.FormatInt(r.Reclaimed+r.WALBytes, 10))
+	sb.WriteString(" bytes")
+	if r.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(r.Err.Error())
+	}
+	return sb.String()
+}
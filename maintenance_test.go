@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenance(t *testing.T) {
+	type note struct {
+		ID   *int
+		Text string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("note", &note{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("x", 1024)
+	for i := 0; i < 256; i++ {
+		err = db.Insert("note", &note{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Del("note", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Analyze("note")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := make(chan MaintenanceReport, 8)
+	err = db.StartMaintenance(MaintenanceOptions{
+		Interval:      10 * time.Millisecond,
+		Optimize:      true,
+		FreelistBytes: 64 * 1024,
+		OnReport:      func(r MaintenanceReport) { reports <- r },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-reports
+	db.StopMaintenance()
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.Reclaimed < 64*1024 || len(r.Ran) != 2 || r.Ran[1] != "vacuum" {
+		t.Fatal("unexpected", r)
+	}
+	t.Log(r)
+	_, err = db.Exec("PRAGMA journal_mode = WAL;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("note", &note{Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := db.WALCheckpoint(CheckpointTruncate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Busy || cr.Log != 0 {
+		t.Fatal("unexpected", cr)
+	}
+	_, err = db.Exec("PRAGMA journal_mode = DELETE;")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// memoryquery 共享缓存内存数据库 URI 的参数
+const memoryquery = "?mode=memory&cache=shared"
+
+// NewMemory 创建名为 name 的内存数据库.
+// 连接池中的所有连接, 以及进程内同名的其它内存数据库看到同一份数据,
+// 所有同名数据库关闭后数据丢失. 共享缓存使用表级锁, 并发写入时可能返回 SQLITE_LOCKED.
+func NewMemory(name string, opts ...Option) Sqlite {
+	return New("file:"+url.PathEscape(name)+memoryquery, opts...)
+}
+
+// ismemory 判断 dbpath 是否为共享缓存内存数据库
+func ismemory(dbpath string) bool {
+	return strings.HasPrefix(dbpath, "file:") && strings.Contains(dbpath, "mode=memory")
+}
+
+// SaveTo 将数据库保存到 path, 常用于持久化内存数据库, 见 Backup.
+func (db *Sqlite) SaveTo(path string) error {
+	return db.Backup(path, nil)
+}
+
+type restorer interface {
+	NewRestore(string) (*sqlite.Backup, error)
+}
+
+// LoadFrom 用 path 处的数据库替换当前数据库的全部内容, 常用于加载内存数据库.
+// 等待进行中的操作结束, 加载期间其它操作返回 ErrClosed.
+func (db *Sqlite) LoadFrom(path string) error {
+	if err := db.checkref(); err != nil {
+		return err
+	}
+	db = db.root()
+	// NewRestore 会创建不存在的文件
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	return db.exclusive(func() error {
+		defer db.stmtcache.clear()
+		conn, err := db.db.Conn(context.Background())
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return conn.Raw(func(dc any) error {
+			rc, ok := dc.(restorer)
+			if !ok {
+				return errors.New("sqlite: driver does not support online backup")
+			}
+			b, err := rc.NewRestore(path)
+			if err != nil {
+				return err
+			}
+			for more := true; more; {
+				more, err = b.Step(-1)
+				if err != nil {
+					_ = b.Finish()
+					return err
+				}
+			}
+			return b.Finish()
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	db := NewMemory("testmemory")
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("user", &user{ID: 1, Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 连接池中的每个连接看到同一数据库
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conns[i], err = db.db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range conns {
+		var n int
+		err = conn.QueryRowContext(context.Background(), "SELECT COUNT(1) FROM user;").Scan(&n)
+		if err != nil || n != 1 {
+			t.Fatal(n, err)
+		}
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	path := filepath.Join(t.TempDir(), "saved.db")
+	err = db.SaveTo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = NewMemory("testmemory")
+	err = db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tables, _ := db.ListTables()
+	if len(tables) != 0 {
+		t.Fatal("memory db should be empty after close", tables)
+	}
+	err = db.LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := Find[user](&db, "user", "WHERE ID = 1")
+	if err != nil || u.Name != "Anna" {
+		t.Fatal(u, err)
+	}
+	if err = db.LoadFrom(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	// Restore 不能关闭内存数据库, 应同样在线加载
+	err = db.Insert("user", &user{ID: 2, Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Restore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Count("user")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+}
@@ -0,0 +1,206 @@
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics 接收数据库的运行指标.
+// 实现需并发安全, 可桥接到 Prometheus 等监控系统, 内置实现见 Collector.
+type Metrics interface {
+	// ObserveQuery 一条语句执行完毕, op 为发起语句的操作, 如 Insert, Find
+	ObserveQuery(op, table string, d time.Duration, err error)
+	// ObserveStmtCache 编译语句时语句缓存是否命中
+	ObserveStmtCache(hit bool)
+	// ObserveStmtEvict 语句因过期或关闭数据库被移出缓存
+	ObserveStmtEvict()
+	// ObserveBusyRetry 语句因 SQLITE_BUSY 重试了一次
+	ObserveBusyRetry(op string)
+	// ObserveTx 事务结束, committed 为 false 时为回滚或提交失败
+	ObserveTx(op string, committed bool)
+}
+
+// WithMetrics 将运行指标交给 m
+func WithMetrics(m Metrics) Option {
+	return func(db *Sqlite) {
+		db.hooks.metrics = m
+	}
+}
+
+// WithBusyRetry 在语句返回 SQLITE_BUSY 时每隔 wait 重试, 至多 n 次.
+// 事务中的语句不会重试.
+func WithBusyRetry(n int, wait time.Duration) Option {
+	return func(db *Sqlite) {
+		db.busyretry = n
+		db.busywait = wait
+	}
+}
+
+// PoolStats 返回连接池状态
+func (db *Sqlite) PoolStats() sql.DBStats {
+	if db.acquire() != nil {
+		return sql.DBStats{}
+	}
+	defer db.release()
+	db = db.root()
+	return db.db.Stats()
+}
+
+// sqlitebusy SQLITE_BUSY 的主错误码
+const sqlitebusy = 5
+
+// isbusy 判断 err 是否为 SQLITE_BUSY
+func isbusy(err error) bool {
+	var e interface{ Code() int }
+	return errors.As(err, &e) && e.Code()&0xff == sqlitebusy
+}
+
+// retry 调用 f, 返回 SQLITE_BUSY 时按 WithBusyRetry 的选项重试
+func retry[T any](db *Sqlite, op string, f func() (T, error)) (v T, err error) {
+	db = db.root()
+	for i := 0; ; i++ {
+		v, err = f()
+		if i >= db.busyretry || !isbusy(err) {
+			return
+		}
+		if m := db.hooks.metrics; m != nil {
+			m.ObserveBusyRetry(op)
+		}
+		time.Sleep(db.busywait)
+	}
+}
+
+// endtx 提交或回滚 tx, 上报事务结果
+func (db *Sqlite) endtx(op string, tx *sql.Tx, commit bool) (err error) {
+	if commit {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	if m := db.root().hooks.metrics; m != nil {
+		m.ObserveTx(op, commit && err == nil)
+	}
+	return
+}
+
+// OpStats 一种操作的语句统计
+type OpStats struct {
+	Count  int64
+	Errors int64
+	Total  time.Duration // 总耗时
+	Max    time.Duration // 最长耗时
+}
+
+// MetricsSnapshot Collector 在某一时刻的指标
+type MetricsSnapshot struct {
+	Ops           map[string]OpStats // 操作名 -> 统计
+	StmtHits      int64
+	StmtMisses    int64
+	StmtEvictions int64
+	BusyRetries   int64
+	TxCommits     int64
+	TxRollbacks   int64
+	Pool          *sql.DBStats `json:",omitempty"`
+}
+
+// Collector 内置的 Metrics 实现, 在内存中累计指标.
+// 实现了 expvar.Var, 可直接 expvar.Publish.
+type Collector struct {
+	// Pool 非 nil 时 Snapshot 包括其返回的连接池状态, 如 db.PoolStats
+	Pool func() sql.DBStats
+
+	mu  sync.Mutex
+	ops map[string]*OpStats
+
+	hits, misses, evictions atomic.Int64
+	busy                    atomic.Int64
+	commits, rollbacks      atomic.Int64
+}
+
+// NewCollector 创建 Collector
+func NewCollector() *Collector {
+	return &Collector{ops: make(map[string]*OpStats)}
+}
+
+// ObserveQuery 实现 Metrics
+func (c *Collector) ObserveQuery(op, _ string, d time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.ops[op]
+	if s == nil {
+		s = &OpStats{}
+		c.ops[op] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+}
+
+// ObserveStmtCache 实现 Metrics
+func (c *Collector) ObserveStmtCache(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// ObserveStmtEvict 实现 Metrics
+func (c *Collector) ObserveStmtEvict() {
+	c.evictions.Add(1)
+}
+
+// ObserveBusyRetry 实现 Metrics
+func (c *Collector) ObserveBusyRetry(string) {
+	c.busy.Add(1)
+}
+
+// ObserveTx 实现 Metrics
+func (c *Collector) ObserveTx(_ string, committed bool) {
+	if committed {
+		c.commits.Add(1)
+	} else {
+		c.rollbacks.Add(1)
+	}
+}
+
+// Snapshot 返回当前的指标
+func (c *Collector) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		StmtHits:      c.hits.Load(),
+		StmtMisses:    c.misses.Load(),
+		StmtEvictions: c.evictions.Load(),
+		BusyRetries:   c.busy.Load(),
+		TxCommits:     c.commits.Load(),
+		TxRollbacks:   c.rollbacks.Load(),
+	}
+	c.mu.Lock()
+	s.Ops = make(map[string]OpStats, len(c.ops))
+	for op, st := range c.ops {
+		s.Ops[op] = *st
+	}
+	c.mu.Unlock()
+	if c.Pool != nil {
+		p := c.Pool()
+		s.Pool = &p
+	}
+	return s
+}
+
+// String 以 json 返回 Snapshot, 实现 expvar.Var
+func (c *Collector) String() string {
+	data, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
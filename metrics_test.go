@@ -0,0 +1,89 @@
+package sql
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	c := NewCollector()
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := New(dbpath, WithMetrics(c), WithBusyRetry(2, time.Millisecond))
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c.Pool = db.PoolStats
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 3; i++ {
+		err = db.Insert("user", &user{ID: i, Name: "Anna"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = db.Find("user", &user{}, "WHERE ID = 100")
+	_, err = ImportJSONL[user](&db, "user", strings.NewReader(`{"ID":5,"Name":"Bob"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 另一连接持有写锁时插入返回 SQLITE_BUSY 并重试
+	other := New(dbpath)
+	err = other.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	tx, err := other.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec("INSERT INTO user VALUES (9, 'Lock');")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("user", &user{ID: 10, Name: "Busy"})
+	_ = tx.Rollback()
+	if !isbusy(err) {
+		t.Fatal("expected SQLITE_BUSY, got", err)
+	}
+
+	s := c.Snapshot()
+	// 每次 Insert 先查询列名再插入
+	if ins := s.Ops["Insert"]; ins.Count != 8 || ins.Errors != 1 {
+		t.Fatal("unexpected Insert stats", ins)
+	}
+	if find := s.Ops["Find"]; find.Count != 1 || find.Errors != 0 {
+		t.Fatal("unexpected Find stats", find)
+	}
+	if s.StmtMisses == 0 || s.StmtHits == 0 {
+		t.Fatal("unexpected stmt cache stats", s)
+	}
+	// 每次编译只计一次命中或未命中
+	if st := db.StmtCacheStats(); st.Hits != s.StmtHits || st.Misses != s.StmtMisses {
+		t.Fatal("stmt cache stats mismatch", st, s)
+	}
+	if s.BusyRetries != 2 || s.TxCommits != 1 || s.TxRollbacks != 0 {
+		t.Fatal("unexpected counters", s)
+	}
+	if s.Pool == nil || s.Pool.OpenConnections == 0 {
+		t.Fatal("missing pool stats", s.Pool)
+	}
+	var decoded MetricsSnapshot
+	err = json.NewDecoder(bytes.NewReader([]byte(c.String()))).Decode(&decoded)
+	if err != nil || decoded.Ops["Insert"].Count != 8 {
+		t.Fatal("unexpected expvar output", c.String(), err)
+	}
+}
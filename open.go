@@ -0,0 +1,38 @@
+package sql
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// WithMkdirAll 打开数据库前以 perm 创建其所在的目录
+func WithMkdirAll(perm os.FileMode) Option {
+	return func(db *Sqlite) {
+		db.dirperm = perm
+	}
+}
+
+// WithLazyOpen 在第一次操作时以 cachettl 打开数据库, 无需调用 Open.
+// 打开失败时返回错误, 下次操作时重试. 显式 Close 后不会再次自动打开.
+func WithLazyOpen(cachettl time.Duration) Option {
+	return func(db *Sqlite) {
+		db.lazy = true
+		db.lazyttl = cachettl
+	}
+}
+
+// MustOpen 创建并打开数据库, 失败时 panic. 用于初始化代码.
+func MustOpen(dbpath string, cachettl time.Duration, opts ...Option) *Sqlite {
+	db := New(dbpath, opts...)
+	err := db.Open(cachettl)
+	if err != nil {
+		panic(err)
+	}
+	return &db
+}
+
+// isfilepath 判断 dbpath 是否为普通文件路径, 而非 URI 或内存数据库
+func isfilepath(dbpath string) bool {
+	return dbpath != "" && dbpath != ":memory:" && !strings.HasPrefix(dbpath, "file:")
+}
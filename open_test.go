@@ -0,0 +1,101 @@
+package sql
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMkdirAndMustOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b", "test.db")
+	db := MustOpen(path, time.Hour, WithMkdirAll(0o750))
+	defer db.Close()
+	fi, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o750 {
+		t.Fatal("unexpected dir permission", fi.Mode().Perm())
+	}
+	_, err = db.ListTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustOpen should panic")
+		}
+	}()
+	_ = MustOpen(filepath.Join(dir, "missing", "test.db"), time.Hour, WithQuickCheck())
+}
+
+func TestLazyOpen(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	path := filepath.Join(t.TempDir(), "data", "test.db")
+	// 每次打开执行一次 QuickCheck, 由 Collector 计数
+	c := NewCollector()
+	db := New(path, WithMkdirAll(0o755), WithLazyOpen(time.Hour), WithQuickCheck(), WithMetrics(c))
+	defer db.Close()
+	// 并发的第一次操作只打开一次.
+	// 这里并发的是读操作, 不会返回 SQLITE_BUSY; 并发的第一次写入与打开无关,
+	// 仍可能相互返回 SQLITE_BUSY, 需要时使用 WithBusyRetry.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.ListTables()
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := c.Snapshot().Ops["QuickCheck"].Count; n != 1 {
+		t.Fatal("opened", n, "times")
+	}
+	err := db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("user", &user{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Count("user")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	_ = db.Close()
+	if _, err = db.Count("user"); err != ErrClosed {
+		t.Fatal("lazy open should not reopen after Close, got", err)
+	}
+}
+
+func TestOpenTwice(t *testing.T) {
+	dir := t.TempDir()
+	db := New(filepath.Join(dir, "test.db"))
+	if err := db.RestoreSnapshot(dir, nil); err != ErrNilDB {
+		t.Fatal("restore on unopened db, got", err)
+	}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Open(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Open(time.Hour, WithQuickCheck())
+	if err != ErrOpened {
+		t.Fatal("options on open db, got", err)
+	}
+}
@@ -0,0 +1,182 @@
+package sql
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// Period 分区的时间跨度
+type Period int
+
+const (
+	// Daily 每天一个分区, 键为 20060102
+	Daily Period = iota
+	// Weekly 每周一个分区, 从周一开始, 键为周一的 20060102
+	Weekly
+	// Monthly 每月一个分区, 键为 200601
+	Monthly
+)
+
+// layout 返回分区键的格式
+func (p Period) layout() string {
+	if p == Monthly {
+		return "200601"
+	}
+	return "20060102"
+}
+
+// start 返回 t 所在分区的开始时间
+func (p Period) start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch p {
+	case Weekly:
+		d -= (int(t.Weekday()) + 6) % 7
+	case Monthly:
+		d = 1
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// next 返回从 start 开始的分区的下一个分区的开始时间
+func (p Period) next(start time.Time) time.Time {
+	switch p {
+	case Weekly:
+		return start.AddDate(0, 0, 7)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Partitioned 按时间分区的表, 每个周期一张表, 结构均为 T.
+// 分区由 T 中保存 Unix 秒时间戳的整数字段决定, 按本地时间划分.
+type Partitioned[T any] struct {
+	family *TableFamily[T]
+	period Period
+	column string
+	index  []int // 时间戳字段的反射下标
+}
+
+// NewPartitioned 创建以 template 为名称模板, 按 period 划分的分区表, 见 TableFamily.
+// timefield 为时间戳字段的列名, additional 在建分区时传给 Create.
+func NewPartitioned[T any](db *Sqlite, template string, period Period, timefield string, additional ...string) (*Partitioned[T], error) {
+	for _, f := range fieldsof(reflect.TypeOf((*T)(nil)).Elem()) {
+		if f.name != timefield {
+			continue
+		}
+		switch reflect.TypeOf((*T)(nil)).Elem().FieldByIndex(f.index).Type.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			return &Partitioned[T]{
+				family: NewTableFamily[T](db, template, additional...),
+				period: period, column: timefield, index: f.index,
+			}, nil
+		default:
+			return nil, errors.New("sqlite: time field " + timefield + " is not an integer")
+		}
+	}
+	return nil, errors.New("sqlite: invalid time field " + timefield)
+}
+
+// Table 返回 t 所在分区的表名
+func (p *Partitioned[T]) Table(t time.Time) string {
+	return p.family.Table(p.period.start(t.Local()).Format(p.period.layout()))
+}
+
+// Insert 按时间戳插入 obj 到所在的分区, 分区不存在时先建表.
+// 如果 PK 存在会覆盖.
+// 返回错误.
+func (p *Partitioned[T]) Insert(obj *T) error {
+	var ts int64
+	v := reflect.ValueOf(obj).Elem().FieldByIndex(p.index)
+	if v.CanInt() {
+		ts = v.Int()
+	} else {
+		ts = int64(v.Uint())
+	}
+	return p.family.Insert(p.period.start(time.Unix(ts, 0)).Format(p.period.layout()), obj)
+}
+
+// partition 数据库中已有的一个分区
+type partition struct {
+	table      string
+	start, end time.Time
+}
+
+// partitions 列出所有分区, 按时间排序
+func (p *Partitioned[T]) partitions() (ps []partition, err error) {
+	members, err := p.family.Members("")
+	if err != nil {
+		return
+	}
+	for _, t := range members {
+		key, _ := p.family.Key(t)
+		start, err := time.ParseInLocation(p.period.layout(), key, time.Local)
+		if err != nil || !p.period.start(start).Equal(start) {
+			continue
+		}
+		ps = append(ps, partition{table: t, start: start, end: p.period.next(start)})
+	}
+	return
+}
+
+// Partitions 列出所有分区的表名, 按时间排序
+func (p *Partitioned[T]) Partitions() ([]string, error) {
+	ps, err := p.partitions()
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, len(ps))
+	for i, pt := range ps {
+		tables[i] = pt.table
+	}
+	return tables, nil
+}
+
+// Range 查询时间戳在 [from, to) 内的行, 只查询与该区间重叠的分区.
+// condition 为附加的条件表达式, 可为空或"UserID = ?".
+// 默认字段与结构体元素顺序一致.
+// 返回错误.
+func (p *Partitioned[T]) Range(from, to time.Time, condition string, questions ...any) ([]*T, error) {
+	ps, err := p.partitions()
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, pt := range ps {
+		if pt.start.Before(to) && pt.end.After(from) {
+			tables = append(tables, pt.table)
+		}
+	}
+	col := quoteident(p.column)
+	q := "WHERE " + col + " >= ? AND " + col + " < ?"
+	if condition != "" {
+		q += " AND (" + condition + ")"
+	}
+	rows, err := p.family.unionof(tables, q, append([]any{from.Unix(), to.Unix()}, questions...))
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]*T, len(rows))
+	for i, r := range rows {
+		vals[i] = r.Row
+	}
+	return vals, nil
+}
+
+// Retain 删除结束时间早于 window 之前的分区, 即只保留最近 window 内的数据.
+// 返回删除的分区数+错误
+func (p *Partitioned[T]) Retain(window time.Duration) (int, error) {
+	ps, err := p.partitions()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-window)
+	var tables []string
+	for _, pt := range ps {
+		if !pt.end.After(cutoff) {
+			tables = append(tables, pt.table)
+		}
+	}
+	return p.family.droptables(tables)
+}
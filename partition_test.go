@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPartitioned(t *testing.T) {
+	type message struct {
+		ID   int64
+		Time int64
+		User int64
+		Text string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = NewPartitioned[message](&db, "msg_*", Daily, "Text")
+	if err == nil {
+		t.Fatal("string time field accepted")
+	}
+	p, err := NewPartitioned[message](&db, "msg_*", Daily, "Time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := Daily.start(time.Now())
+	for i := 0; i < 10; i++ {
+		ts := today.AddDate(0, 0, -i).Add(time.Hour)
+		for u := int64(1); u <= 2; u++ {
+			err = p.Insert(&message{ID: int64(i)*10 + u, Time: ts.Unix(), User: u, Text: "hi"})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tables, err := p.Partitions()
+	if err != nil || len(tables) != 10 {
+		t.Fatal(tables, err)
+	}
+	if tables[9] != p.Table(time.Now()) || tables[9] != "msg_"+today.Format("20060102") {
+		t.Fatal(tables[9])
+	}
+	// 最近 3 天中用户 1 的消息
+	msgs, err := p.Range(today.AddDate(0, 0, -2), today.AddDate(0, 0, 1), "User = ?", 1)
+	if err != nil || len(msgs) != 3 {
+		t.Fatal(msgs, err)
+	}
+	for _, m := range msgs {
+		if m.User != 1 {
+			t.Fatal(m)
+		}
+	}
+	// 区间从分区中间开始
+	msgs, err = p.Range(today.Add(2*time.Hour), today.AddDate(0, 0, 1), "")
+	if err != ErrNullResult {
+		t.Fatal(msgs, err)
+	}
+	n, err := p.Retain(5 * 24 * time.Hour)
+	// 结束于 5 天前之前的分区, 即 6 到 9 天前
+	if err != nil || n != 4 {
+		t.Fatal(n, err)
+	}
+	tables, err = p.Partitions()
+	if err != nil || len(tables) != 10-n {
+		t.Fatal(tables, err)
+	}
+
+	w, err := NewPartitioned[message](&db, "week_*", Weekly, "Time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 7; i++ {
+		if w.Table(monday.AddDate(0, 0, i)) != "week_20240101" {
+			t.Fatal(w.Table(monday.AddDate(0, 0, i)))
+		}
+	}
+	if w.Table(monday.AddDate(0, 0, 7)) != "week_20240108" {
+		t.Fatal(w.Table(monday.AddDate(0, 0, 7)))
+	}
+	m, err := NewPartitioned[message](&db, "month_*", Monthly, "Time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Table(monday.AddDate(0, 1, 5)) != "month_202402" {
+		t.Fatal(m.Table(monday.AddDate(0, 1, 5)))
+	}
+}
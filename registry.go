@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// registry 进程内的共享数据库, 键为 registrykey
+var registry = struct {
+	sync.Mutex
+	dbs map[string]*shareddb
+}{dbs: make(map[string]*shareddb)}
+
+type shareddb struct {
+	db   *Sqlite
+	refs int
+}
+
+// shareref Shared 返回的一个引用, 只能释放一次
+type shareref struct {
+	key      string
+	once     sync.Once
+	released atomic.Bool // 释放后的操作返回 ErrClosed
+}
+
+// Shared 返回进程内 dbpath 处的共享数据库, 不存在时以 cachettl 与 opts 新建并打开.
+// 指向同一文件的不同路径共享同一数据库, 此时忽略 cachettl 与 opts.
+// 每次调用返回各自的引用, 其 Close 只释放一次本引用, 最后一个引用 Close 时才关闭数据库.
+func Shared(dbpath string, cachettl time.Duration, opts ...Option) (*Sqlite, error) {
+	key := registrykey(dbpath)
+	registry.Lock()
+	defer registry.Unlock()
+	if s, ok := registry.dbs[key]; ok {
+		s.refs++
+		return s.ref(key), nil
+	}
+	db := New(dbpath, opts...)
+	err := db.Open(cachettl)
+	if err != nil {
+		return nil, err
+	}
+	s := &shareddb{db: &db, refs: 1}
+	registry.dbs[key] = s
+	return s.ref(key), nil
+}
+
+// ref 返回 s 的一个新引用
+func (s *shareddb) ref(key string) *Sqlite {
+	return &Sqlite{parent: s.db, share: &shareref{key: key}}
+}
+
+// unshare 释放 db 持有的共享引用, 只有第一次调用生效.
+// 返回是否为最后一个引用, 此时应关闭数据库.
+func (db *Sqlite) unshare() (last bool) {
+	db.share.once.Do(func() {
+		db.share.released.Store(true)
+		registry.Lock()
+		defer registry.Unlock()
+		s, ok := registry.dbs[db.share.key]
+		if !ok || s.db != db.parent {
+			return
+		}
+		s.refs--
+		if s.refs == 0 {
+			delete(registry.dbs, db.share.key)
+			last = true
+		}
+	})
+	return
+}
+
+// registrykey 返回 dbpath 指向的文件的绝对路径, URI 与内存数据库原样返回
+func registrykey(dbpath string) string {
+	if !isfilepath(dbpath) {
+		return dbpath
+	}
+	p, err := filepath.Abs(dbpath)
+	if err != nil {
+		return dbpath
+	}
+	if r, err := filepath.EvalSymlinks(p); err == nil {
+		return r
+	}
+	return p
+}
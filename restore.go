@@ -0,0 +1,139 @@
+package sql
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// walfiles 数据库文件附带的日志文件后缀
+var walfiles = [...]string{"-wal", "-shm", "-journal"}
+
+// Restore 用 srcpath 处的备份替换当前打开的数据库.
+// 关闭数据库并清空语句缓存, 原子替换文件后重新打开.
+// 新文件未通过 PRAGMA integrity_check 时回滚到原文件并返回 ErrCorrupt.
+// 先等待进行中的操作结束, 恢复期间其它操作返回 ErrClosed, 定时快照会等待恢复完成.
+// 内存数据库关闭后数据即丢失, 因此改用 LoadFrom 在线加载.
+func (db *Sqlite) Restore(srcpath string) error {
+	if err := db.checkref(); err != nil {
+		return err
+	}
+	db = db.root()
+	if s := db.loadsnapshotter(); s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	if ismemory(db.dbpath) {
+		return db.LoadFrom(srcpath)
+	}
+	tmp, err := copytemp(srcpath, db.dbpath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return db.exclusive(func() error {
+		return db.replace(tmp)
+	})
+}
+
+// replace 关闭数据库, 用 tmp 原子地替换数据库文件后重新打开.
+// 原文件保留为 .prev 直至新文件通过检查, 替换过程中 dbpath 处始终有完整的数据库.
+// Attach 的数据库在重新打开后仍然附加.
+func (db *Sqlite) replace(tmp string) error {
+	attached := db.connector.list()
+	err := db.close()
+	if err != nil {
+		return err
+	}
+	prev := db.dbpath + ".prev"
+	err = keepdb(db.dbpath, prev)
+	if err != nil {
+		return errors.Join(err, db.open(attached...))
+	}
+	err = os.Rename(tmp, db.dbpath)
+	if err == nil {
+		err = db.open(attached...)
+	}
+	if err == nil {
+		err = integrity(db.db)
+	}
+	if err != nil {
+		_ = db.close()
+		return errors.Join(err, swapdb(prev, db.dbpath), db.open(attached...))
+	}
+	removedb(prev)
+	return nil
+}
+
+// copytemp 将 src 复制到 dst 同目录下的临时文件, 返回临时文件路径
+func copytemp(src, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// keepdb 将 path 处数据库的日志文件移至 to, 并将数据库文件硬链接或复制到 to,
+// 之后可直接用新文件原子地覆盖 path. to 处的旧文件会被清除.
+func keepdb(path, to string) (err error) {
+	removedb(to)
+	for _, suffix := range walfiles {
+		err = os.Rename(path+suffix, to+suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+	err = os.Link(path, to)
+	if err == nil {
+		return nil
+	}
+	tmp, err := copytemp(path, to)
+	if err == nil {
+		err = os.Rename(tmp, to)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		for _, suffix := range walfiles {
+			_ = os.Rename(to+suffix, path+suffix)
+		}
+	}
+	return
+}
+
+// swapdb 用 from 处的数据库及其日志文件原子地覆盖 to 处的数据库
+func swapdb(from, to string) error {
+	for _, suffix := range walfiles {
+		_ = os.Remove(to + suffix)
+		err := os.Rename(from+suffix, to+suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(from, to)
+}
+
+// removedb 删除数据库文件及其日志文件
+func removedb(path string) {
+	for _, suffix := range walfiles {
+		_ = os.Remove(path + suffix)
+	}
+	_ = os.Remove(path)
+}
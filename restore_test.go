@@ -0,0 +1,122 @@
+package sql
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	type counter struct {
+		ID    *int
+		Count uint
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("counter", &counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 16; i++ {
+		err = db.Insert("counter", &counter{Count: uint(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	bak := filepath.Join(dir, "backup.db")
+	err = db.Backup(bak, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Del("counter", "WHERE Count > 8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Restore(bak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Count("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 16 {
+		t.Fatal("unexpected count", n)
+	}
+	bad := filepath.Join(dir, "bad.db")
+	err = os.WriteFile(bad, make([]byte, 4096), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Del("counter", "WHERE Count > 8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Restore(bad)
+	if err == nil {
+		t.Fatal("unexpected restore")
+	}
+	n, err = db.Count("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 {
+		t.Fatal("rollback failed, count", n)
+	}
+	if _, err = os.Stat(dbpath + ".prev"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("unexpected prev file", err)
+	}
+}
+
+func TestRestoreWhileSnapshotting(t *testing.T) {
+	type counter struct {
+		ID    *int
+		Count uint
+	}
+	db := Sqlite{dbpath: filepath.Join(t.TempDir(), "test.db")}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("counter", &counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bak := filepath.Join(t.TempDir(), "backup.db")
+	err = db.Backup(bak, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.StartSnapshots(SnapshotOptions{Dir: t.TempDir(), Interval: time.Millisecond, Retention: Retention{Hourly: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.StopSnapshots()
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			if err := db.Restore(bak); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("restore deadlocked with snapshots")
+	}
+}
@@ -0,0 +1,210 @@
+package sql
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Column 表中的列, 对应 PRAGMA table_xinfo, 包括生成列
+type Column struct {
+	CID     int
+	Name    string
+	Type    string
+	NotNull bool
+	Default *string
+	PK      int // 在主键中的序号, 从 1 开始, 0 表示非主键
+}
+
+// ForeignKey 外键, 对应 PRAGMA foreign_key_list
+type ForeignKey struct {
+	ID       int
+	Seq      int
+	Table    string
+	From     string
+	To       *string // 引用对方主键时为 nil
+	OnUpdate string
+	OnDelete string
+	Match    string
+}
+
+// Trigger 触发器
+type Trigger struct {
+	Name  string
+	Table string
+	SQL   string
+}
+
+// TableSchema 表结构
+type TableSchema struct {
+	Name        string
+	Columns     []Column
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+	Triggers    []Trigger
+}
+
+// DescribeTable 返回表结构.
+// 表不存在时返回 ErrNullResult.
+func (db *Sqlite) DescribeTable(table string) (*TableSchema, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	// pragma_table_info 不列出生成列, 仅跳过虚拟表的隐藏列
+	cols, err := queryall[Column](db, "DescribeTable", table, `SELECT cid, name, type, "notnull", dflt_value, pk FROM pragma_table_xinfo(?) WHERE hidden != 1;`, table)
+	if err != nil {
+		return nil, err
+	}
+	s := &TableSchema{Name: table, Columns: make([]Column, len(cols))}
+	for i, c := range cols {
+		s.Columns[i] = *c
+	}
+	s.Indexes, err = db.ListIndexes(table)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := queryall[ForeignKey](db, "DescribeTable", table, "SELECT * FROM pragma_foreign_key_list(?) ORDER BY id, seq;", table)
+	if err != nil && err != ErrNullResult {
+		return nil, err
+	}
+	s.ForeignKeys = make([]ForeignKey, len(fks))
+	for i, fk := range fks {
+		s.ForeignKeys[i] = *fk
+	}
+	s.Triggers, err = db.listtriggers("where type='trigger' and tbl_name=? order by name", table)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListViews 列出所有视图名
+// 返回所有视图名+错误
+func (db *Sqlite) ListViews() (s []string, err error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	type view struct {
+		Name string
+	}
+	views, err := queryall[view](db, "ListViews", "", "SELECT name FROM sqlite_master where type='view' order by name;")
+	if err == ErrNullResult {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	s = make([]string, len(views))
+	for i, v := range views {
+		s[i] = v.Name
+	}
+	return
+}
+
+// ListTriggers 列出所有触发器
+// 返回所有触发器+错误
+func (db *Sqlite) ListTriggers() ([]Trigger, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	return db.listtriggers("where type='trigger' order by name")
+}
+
+func (db *Sqlite) listtriggers(condition string, args ...any) ([]Trigger, error) {
+	trs, err := queryall[Trigger](db, "ListTriggers", "", "SELECT name, tbl_name, sql FROM sqlite_master "+condition+";", args...)
+	if err == ErrNullResult {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := make([]Trigger, len(trs))
+	for i, tr := range trs {
+		s[i] = *tr
+	}
+	return s, nil
+}
+
+// DiffKind 表结构差异类型
+type DiffKind string
+
+const (
+	DiffMissing  DiffKind = "missing"  // 结构体有而表中无
+	DiffExtra    DiffKind = "extra"    // 表中有而结构体无
+	DiffPosition DiffKind = "position" // 列顺序不一致, 影响 SELECT * 的映射
+	DiffType     DiffKind = "type"     // 类型不一致
+	DiffNotNull  DiffKind = "notnull"  // NOT NULL 约束不一致
+	DiffPK       DiffKind = "pk"       // 主键不一致
+)
+
+// SchemaDiff 表结构与结构体定义的一处差异
+type SchemaDiff struct {
+	Column   string
+	Kind     DiffKind
+	Expected string
+	Actual   string
+}
+
+func (d SchemaDiff) String() string {
+	return d.Column + ": " + string(d.Kind) + " (expected " + d.Expected + ", actual " + d.Actual + ")"
+}
+
+// CompareSchema 比较表结构与 objptr 的结构体定义.
+// 结构一致时返回空切片.
+func (db *Sqlite) CompareSchema(table string, objptr any) ([]SchemaDiff, error) {
+	s, err := db.DescribeTable(table)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tags     = tags(objptr)
+		kinds    = kinds(objptr)
+		pks, _   = primarykeys(fieldsof(reflect.TypeOf(objptr).Elem()))
+		actual   = make(map[string]int, len(s.Columns))
+		expected = make(map[string]int, len(tags))
+		diffs    []SchemaDiff
+	)
+	for i, c := range s.Columns {
+		actual[strings.ToLower(c.Name)] = i
+	}
+	for i, name := range tags {
+		expected[strings.ToLower(name)] = i
+		j, ok := actual[strings.ToLower(name)]
+		if !ok {
+			diffs = append(diffs, SchemaDiff{Column: name, Kind: DiffMissing, Expected: kinds[i]})
+			continue
+		}
+		c := &s.Columns[j]
+		if j != i {
+			diffs = append(diffs, SchemaDiff{Column: name, Kind: DiffPosition, Expected: strconv.Itoa(i), Actual: strconv.Itoa(j)})
+		}
+		typ, notnull := strings.CutSuffix(kinds[i], " NOT NULL")
+		if !notnull {
+			typ = strings.TrimSuffix(typ, " NULL")
+		}
+		if !strings.EqualFold(typ, c.Type) {
+			diffs = append(diffs, SchemaDiff{Column: name, Kind: DiffType, Expected: typ, Actual: c.Type})
+		}
+		if notnull != c.NotNull {
+			diffs = append(diffs, SchemaDiff{Column: name, Kind: DiffNotNull, Expected: strconv.FormatBool(notnull), Actual: strconv.FormatBool(c.NotNull)})
+		}
+		pk := 0
+		for k, p := range pks {
+			if p == i {
+				pk = k + 1
+			}
+		}
+		if pk != c.PK {
+			diffs = append(diffs, SchemaDiff{Column: name, Kind: DiffPK, Expected: strconv.Itoa(pk), Actual: strconv.Itoa(c.PK)})
+		}
+	}
+	for _, c := range s.Columns {
+		if _, ok := expected[strings.ToLower(c.Name)]; !ok {
+			diffs = append(diffs, SchemaDiff{Column: c.Name, Kind: DiffExtra, Actual: c.Type})
+		}
+	}
+	return diffs, nil
+}
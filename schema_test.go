@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDescribeTable(t *testing.T) {
+	type teacher struct {
+		ID   *int
+		Name string `db:"Name,index"`
+	}
+	type class struct {
+		ID        *int
+		TeacherID int    `db:"TeacherID,references=teacher(ID),on_delete=cascade"`
+		Count     int    `db:"Count,default=30"`
+		Title     string `db:"Title,generated='class ' || ID stored"`
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("teacher", &teacher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("class", &class{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("CREATE VIEW bigclass AS SELECT * FROM class WHERE Count > 50;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("CREATE TRIGGER nonneg BEFORE INSERT ON class BEGIN SELECT RAISE(ABORT, 'neg') WHERE NEW.Count < 0; END;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := db.DescribeTable("class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Columns) != 4 || s.Columns[0].PK != 1 || !s.Columns[1].NotNull || *s.Columns[2].Default != "30" || s.Columns[3].Name != "Title" {
+		t.Fatal("unexpected", s.Columns)
+	}
+	if len(s.ForeignKeys) != 1 || s.ForeignKeys[0].Table != "teacher" || s.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Fatal("unexpected", s.ForeignKeys)
+	}
+	if len(s.Triggers) != 1 || s.Triggers[0].Name != "nonneg" {
+		t.Fatal("unexpected", s.Triggers)
+	}
+	_, err = db.DescribeTable("nosuchtable")
+	if err != ErrNullResult {
+		t.Fatal("unexpected", err)
+	}
+	views, err := db.ListViews()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 1 || views[0] != "bigclass" {
+		t.Fatal("unexpected", views)
+	}
+	diffs, err := db.CompareSchema("teacher", &teacher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatal("unexpected", diffs)
+	}
+	diffs, err = db.CompareSchema("class", &class{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatal("unexpected", diffs)
+	}
+	type teacher2 struct {
+		ID   *int
+		Age  int
+		Name *string
+	}
+	diffs, err = db.CompareSchema("teacher", &teacher2{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 3 || diffs[0].Kind != DiffMissing || diffs[1].Kind != DiffPosition || diffs[2].Kind != DiffNotNull {
+		t.Fatal("unexpected", diffs)
+	}
+}
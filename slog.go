@@ -0,0 +1,53 @@
+//go:build go1.21
+
+package sql
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SlogLogger 用 log/slog 记录语句的 Logger.
+// 出错的语句记为 Error, 耗时不低于 Slow 的记为 Warn, 其余记为 Debug.
+type SlogLogger struct {
+	Logger *slog.Logger  // 为 nil 时使用 slog.Default()
+	Slow   time.Duration // 慢查询阈值, 为 0 时不区分
+}
+
+// NewSlogLogger 返回以 slow 为慢查询阈值的 SlogLogger
+func NewSlogLogger(l *slog.Logger, slow time.Duration) *SlogLogger {
+	return &SlogLogger{Logger: l, Slow: slow}
+}
+
+// LogQuery 实现 Logger
+func (s *SlogLogger) LogQuery(ctx context.Context, e *QueryEvent) {
+	l := s.Logger
+	if l == nil {
+		l = slog.Default()
+	}
+	level, msg := slog.LevelDebug, "sqlite: query"
+	switch {
+	case e.Err != nil:
+		level, msg = slog.LevelError, "sqlite: query failed"
+	case s.Slow > 0 && e.Duration >= s.Slow:
+		level, msg = slog.LevelWarn, "sqlite: slow query"
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	attrs := make([]slog.Attr, 0, 7)
+	attrs = append(attrs, slog.String("op", e.Op))
+	if e.Table != "" {
+		attrs = append(attrs, slog.String("table", e.Table))
+	}
+	attrs = append(attrs, slog.String("sql", e.SQL))
+	if len(e.Args) > 0 {
+		attrs = append(attrs, slog.Any("args", e.Args))
+	}
+	attrs = append(attrs, slog.Duration("duration", e.Duration), slog.Int64("rows", e.Rows))
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("err", e.Err))
+	}
+	l.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,43 @@
+//go:build go1.21
+
+package sql
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogLogger(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	var buf bytes.Buffer
+	sl := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})), time.Nanosecond)
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := New(dbpath, WithLogger(sl, &LogOptions{NoArgs: true}))
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("user", &user{ID: 1, Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "sqlite: slow query") || !strings.Contains(out, "op=Insert") {
+		t.Fatal("missing slow query log", out)
+	}
+	if strings.Contains(out, "Anna") {
+		t.Fatal("args should not be logged", out)
+	}
+}
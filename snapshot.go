@@ -0,0 +1,360 @@
+package sql
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// snapshotlayout 快照文件名中的时间格式, 按字典序即时间序
+const snapshotlayout = "20060102T150405.000Z"
+
+// Compressor 快照压缩算法.
+// 内置 Gzip 与 Zstd, 其它算法可由调用方实现.
+type Compressor interface {
+	// Ext 压缩文件的扩展名, 如 ".gz"
+	Ext() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type gzipcompressor struct{}
+
+// Gzip 使用 compress/gzip 压缩快照
+var Gzip Compressor = gzipcompressor{}
+
+func (gzipcompressor) Ext() string { return ".gz" }
+
+func (gzipcompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipcompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdcompressor struct{}
+
+// Zstd 使用纯 Go 实现的 zstd 压缩快照, 比 Gzip 更快且压缩率更高
+var Zstd Compressor = zstdcompressor{}
+
+func (zstdcompressor) Ext() string { return ".zst" }
+
+func (zstdcompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdcompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// Retention 快照保留策略.
+// 分别保留最近 Hourly 个小时, Daily 天, Weekly 周中各自最新的一份,
+// 最新的一份快照总会保留. 全为 0 时不清理.
+type Retention struct {
+	Hourly int
+	Daily  int
+	Weekly int
+}
+
+// SnapshotOptions 定时快照选项
+type SnapshotOptions struct {
+	Dir        string         // 快照目录, 不存在时自动创建
+	Interval   time.Duration  // 快照间隔
+	Compressor Compressor     // 为 nil 时不压缩
+	Retention  Retention      // 保留策略
+	Backup     *BackupOptions // 每次快照使用的备份选项
+}
+
+// SnapshotStatus 快照任务状态, 用于健康检查
+type SnapshotStatus struct {
+	LastSuccess time.Time // 最近一次成功的时间
+	LastPath    string    // 最近一次成功的快照路径
+	LastFailure time.Time // 最近一次失败的时间
+	LastError   error     // 最近一次失败的错误
+}
+
+type snapshotter struct {
+	opt    SnapshotOptions
+	now    func() time.Time
+	mu     sync.Mutex
+	status SnapshotStatus
+	loop   *loop
+}
+
+// StartSnapshots 启动后台定时快照, 已启动时先停止旧任务.
+// 返回错误.
+func (db *Sqlite) StartSnapshots(opt SnapshotOptions) error {
+	return db.startsnapshots(opt, time.Now)
+}
+
+// startsnapshots 启动以 now 为时钟的定时快照
+func (db *Sqlite) startsnapshots(opt SnapshotOptions, now func() time.Time) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+	db = db.root()
+	if opt.Dir == "" || opt.Interval <= 0 {
+		return errors.New("sqlite: invalid snapshot options")
+	}
+	err := os.MkdirAll(opt.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	l := db.life
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	db.stopsnapshots()
+	s := &snapshotter{opt: opt, now: now}
+	s.loop = startloop(opt.Interval, func() { _, _ = db.snapshot(s) })
+	db.snapshotter = s
+	return nil
+}
+
+// StopSnapshots 停止后台定时快照并等待进行中的快照完成
+func (db *Sqlite) StopSnapshots() {
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return
+	}
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	db.stopsnapshots()
+}
+
+// stopsnapshots 停止定时快照, 需持有 tasks
+func (db *Sqlite) stopsnapshots() {
+	s := db.snapshotter
+	if s == nil {
+		return
+	}
+	s.loop.close()
+	db.snapshotter = nil
+}
+
+// loadsnapshotter 返回定时快照任务, 未启动时为 nil
+func (db *Sqlite) loadsnapshotter() *snapshotter {
+	db = db.root()
+	l := db.life
+	if l == nil {
+		return nil
+	}
+	l.tasks.Lock()
+	defer l.tasks.Unlock()
+	return db.snapshotter
+}
+
+// Snapshot 按定时快照的选项立即执行一次快照.
+// 返回快照路径+错误.
+func (db *Sqlite) Snapshot() (string, error) {
+	s := db.loadsnapshotter()
+	if s == nil {
+		return "", errors.New("sqlite: snapshots not started")
+	}
+	return db.snapshot(s)
+}
+
+// SnapshotStatus 返回定时快照的状态
+func (db *Sqlite) SnapshotStatus() (st SnapshotStatus) {
+	s := db.loadsnapshotter()
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	st = s.status
+	s.mu.Unlock()
+	return
+}
+
+// snapshot 执行一次快照并记录状态.
+// 先持有 s.mu 再 acquire, 与 Restore 的加锁顺序一致;
+// db 已关闭或正被独占时跳过且不计为失败.
+func (db *Sqlite) snapshot(s *snapshotter) (path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = db.acquire()
+	if err != nil {
+		return
+	}
+	defer db.release()
+	path, err = db.writesnapshot(&s.opt, s.now())
+	if errors.Is(err, ErrClosed) { // 快照中途被关闭或独占, 不计为失败
+		return
+	}
+	if err != nil {
+		s.status.LastFailure = s.now()
+		s.status.LastError = err
+		return
+	}
+	s.status.LastSuccess = s.now()
+	s.status.LastPath = path
+	err = prunesnapshots(s.opt.Dir, db.snapshotprefix(), s.opt.Retention)
+	if err != nil {
+		s.status.LastFailure = s.now()
+		s.status.LastError = err
+	}
+	return
+}
+
+// snapshotprefix 快照文件名前缀, 取自数据库文件名
+func (db *Sqlite) snapshotprefix() string {
+	db = db.root()
+	return strings.TrimSuffix(filepath.Base(db.dbpath), filepath.Ext(db.dbpath)) + "-"
+}
+
+func (db *Sqlite) writesnapshot(opt *SnapshotOptions, now time.Time) (string, error) {
+	path := filepath.Join(opt.Dir, db.snapshotprefix()+now.UTC().Format(snapshotlayout)+".db")
+	if opt.Compressor == nil {
+		return path, db.Backup(path, opt.Backup)
+	}
+	path += opt.Compressor.Ext()
+	out, err := os.CreateTemp(opt.Dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(out.Name())
+	w, err := opt.Compressor.NewWriter(out)
+	if err == nil {
+		_, err = db.BackupTo(w, opt.Backup)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, os.Rename(out.Name(), path)
+}
+
+// snapshotfile 目录中的一份快照
+type snapshotfile struct {
+	path string
+	at   time.Time
+}
+
+// listsnapshots 按时间从新到旧列出目录中前缀为 prefix 的快照
+func listsnapshots(dir, prefix string) ([]snapshotfile, error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]snapshotfile, 0, len(ents))
+	for _, e := range ents {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		ts, _, _ := strings.Cut(name[len(prefix):], ".db")
+		at, err := time.Parse(snapshotlayout, ts)
+		if err != nil {
+			continue
+		}
+		files = append(files, snapshotfile{path: filepath.Join(dir, name), at: at})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].at.After(files[j].at) })
+	return files, nil
+}
+
+// prunesnapshots 按保留策略删除多余的快照
+func prunesnapshots(dir, prefix string, r Retention) error {
+	if r.Hourly <= 0 && r.Daily <= 0 && r.Weekly <= 0 {
+		return nil
+	}
+	files, err := listsnapshots(dir, prefix)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	keep := make([]bool, len(files))
+	keep[0] = true
+	bucket := func(n int, key func(time.Time) string) {
+		seen := make(map[string]struct{}, n)
+		for i, f := range files {
+			k := key(f.at)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			if len(seen) >= n {
+				return
+			}
+			seen[k] = struct{}{}
+			keep[i] = true
+		}
+	}
+	bucket(r.Hourly, func(t time.Time) string { return t.Format("2006010215") })
+	bucket(r.Daily, func(t time.Time) string { return t.Format("20060102") })
+	bucket(r.Weekly, func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return strconv.Itoa(y) + "W" + strconv.Itoa(w)
+	})
+	for i, f := range files {
+		if !keep[i] {
+			err = os.Remove(f.path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RestoreSnapshot 从 dir 中最新的快照恢复数据库, 见 Restore.
+// c 为快照使用的压缩算法, 未压缩时为 nil.
+func (db *Sqlite) RestoreSnapshot(dir string, c Compressor) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	db.release()
+	db = db.root()
+	files, err := listsnapshots(dir, db.snapshotprefix())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("sqlite: no snapshot in " + dir)
+	}
+	path := files[0].path
+	if c == nil || !strings.HasSuffix(path, c.Ext()) {
+		return db.Restore(path)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r, err := c.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	_, err = io.Copy(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return db.Restore(out.Name())
+}
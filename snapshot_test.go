@@ -0,0 +1,114 @@
+package sql
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSnapshots(t *testing.T) {
+	type counter struct {
+		ID    *int
+		Count uint
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("counter", &counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("counter", &counter{Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	// 固定在同一小时内的时钟, 以免跨越整点时多保留一份
+	var ticks atomic.Int64
+	clock := func() time.Time {
+		return time.Date(2024, 7, 10, 12, 0, 0, 0, time.UTC).Add(time.Duration(ticks.Add(1)) * time.Second)
+	}
+	err = db.startsnapshots(SnapshotOptions{
+		Dir:        dir,
+		Interval:   20 * time.Millisecond,
+		Compressor: Gzip,
+		Retention:  Retention{Hourly: 1},
+	}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	db.StopSnapshots()
+	files, err := listsnapshots(dir, "test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Ext(files[0].path) != ".gz" {
+		t.Fatal("unexpected snapshots", files)
+	}
+	if st := db.SnapshotStatus(); st.LastError != nil {
+		t.Fatal(st.LastError)
+	}
+
+	zdir := t.TempDir()
+	err = db.StartSnapshots(SnapshotOptions{Dir: zdir, Interval: time.Hour, Compressor: Zstd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := db.Snapshot()
+	if err != nil || filepath.Ext(path) != ".zst" {
+		t.Fatal(path, err)
+	}
+	db.StopSnapshots()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := Zstd.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	head := make([]byte, 16)
+	_, err = io.ReadFull(r, head)
+	if err != nil || string(head) != "SQLite format 3\x00" {
+		t.Fatal(string(head), err)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 7, 10, 12, 30, 0, 0, time.UTC)
+	var names []string
+	for i := 0; i < 24*21; i++ { // 三周内每小时一份
+		name := filepath.Join(dir, "bot-"+now.Add(-time.Duration(i)*time.Hour).Format(snapshotlayout)+".db")
+		err := os.WriteFile(name, nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	err := prunesnapshots(dir, "bot-", Retention{Hourly: 3, Daily: 2, Weekly: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := listsnapshots(dir, "bot-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 小时 + 昨天 + 上周日 (今天与本周的最新一份已包含在小时内)
+	if len(files) != 5 {
+		t.Fatal("unexpected", files)
+	}
+	if files[0].path != names[0] || files[3].at.Format("20060102") != "20240709" {
+		t.Fatal("unexpected", files)
+	}
+}
@@ -2,8 +2,11 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -11,53 +14,154 @@ import (
 	"unicode"
 
 	_ "modernc.org/sqlite" // 引入sqlite
-
-	"github.com/FloatTech/ttl"
 )
 
 var (
 	ErrNilDB      = errors.New("sqlite: db is not initialized")
 	ErrNullResult = errors.New("sqlite: null result")
+	ErrInvalidTag = errors.New("sqlite: invalid struct tag")
+	ErrKeyCount   = errors.New("sqlite: key count mismatch")
+	ErrCorrupt    = errors.New("sqlite: integrity check failed")
+	ErrClosed     = errors.New("sqlite: db is closed")
+	ErrOpened     = errors.New("sqlite: options passed to an open db")
 	DriverName    = "sqlite3"
 )
 
 // Sqlite 数据库对象
 type Sqlite struct {
 	db        *sql.DB
+	connector *connector
+	keepalive *sql.Conn // 内存数据库至少保持一个连接, 否则数据丢失
 	dbpath    string
-	stmtcache *ttl.Cache[string, *sql.Stmt]
+	life      *lifecycle
+	cachettl  time.Duration
+	stmtcache *stmtcache
+
+	snapshotter *snapshotter
+	maintainer  *maintainer
+	janitor     *janitor
+	startcheck  *startupcheck
+	hooks       hooks
+	busyretry   int
+	busywait    time.Duration
+	ctx         context.Context
+
+	stmtcachesize int
+	dirperm       os.FileMode
+	lazy          bool
+	lazyttl       time.Duration
+	parent        *Sqlite   // WithContext 视图或 Shared 引用所属的 db
+	share         *shareref // Shared 返回的引用
+	maxidle       int
+}
+
+// Option 数据库选项, 可传给 New 或 Open
+type Option func(*Sqlite)
+
+func New(dbpath string, opts ...Option) Sqlite {
+	db := Sqlite{dbpath: dbpath, life: newlifecycle()}
+	for _, opt := range opts {
+		opt(&db)
+	}
+	return db
 }
 
-func New(dbpath string) Sqlite {
-	return Sqlite{dbpath: dbpath}
+// Open 打开数据库, 已打开时忽略 cachettl, 传入 opts 时返回 ErrOpened.
+// 应在并发使用 db 前调用.
+func (db *Sqlite) Open(cachettl time.Duration, opts ...Option) (err error) {
+	if db.parent != nil {
+		return db.parent.Open(cachettl, opts...)
+	}
+	if db.life == nil {
+		db.life = newlifecycle()
+	}
+	if db.life.transition(stateclosed, stateopening) != stateclosed {
+		if len(opts) > 0 {
+			return ErrOpened
+		}
+		return nil
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	db.cachettl = cachettl
+	err = db.open()
+	db.life.settle(err == nil)
+	if err == nil && db.startcheck != nil {
+		err = db.checkstartup()
+	}
+	return
 }
 
-// Open 打开数据库
-func (db *Sqlite) Open(cachettl time.Duration) (err error) {
+// open 打开连接池, 每个连接附加 attached
+func (db *Sqlite) open(attached ...attachment) (err error) {
 	if db.db == nil {
-		database, err := sql.Open(DriverName, db.dbpath)
+		if db.dirperm != 0 && isfilepath(db.dbpath) {
+			err = os.MkdirAll(filepath.Dir(db.dbpath), db.dirperm)
+			if err != nil {
+				return err
+			}
+		}
+		c, err := newconnector(db.dbpath)
 		if err != nil {
 			return err
 		}
-		db.db = database
+		c.attached = attached
+		database := sql.OpenDB(c)
+		database.SetMaxIdleConns(db.maxidleconns())
+		db.db, db.connector = database, c
+		if ismemory(db.dbpath) {
+			db.keepalive, err = database.Conn(context.Background())
+			if err != nil {
+				_ = database.Close()
+				db.db, db.connector = nil, nil
+				return err
+			}
+		}
 	}
 	if db.stmtcache == nil {
-		db.stmtcache = ttl.NewCacheOn(cachettl, [4]func(string, *sql.Stmt){
-			nil, nil,
-			func(_ string, stmt *sql.Stmt) { _ = stmt.Close() },
-			nil,
+		db.stmtcache = newstmtcache(db.cachettl, db.stmtcachesize, func() {
+			if m := db.hooks.metrics; m != nil {
+				m.ObserveStmtEvict()
+			}
 		})
 	}
 	return
 }
 
-// Close 关闭数据库
+// Close 关闭数据库, 同时停止定时快照, 后台维护与过期行清理.
+// 等待进行中的操作结束, 之后的操作返回 ErrClosed. 已关闭时不做任何事.
+// Shared 返回的引用在最后一个引用 Close 时才关闭数据库, WithContext 视图的 Close 不做任何事.
 func (db *Sqlite) Close() (err error) {
+	if db.parent != nil {
+		if db.share != nil && db.unshare() {
+			return db.parent.Close()
+		}
+		return nil
+	}
+	l := db.life
+	if l == nil || l.transition(stateopen, stateclosing) != stateopen {
+		return nil
+	}
+	db.StopSnapshots()
+	db.StopMaintenance()
+	db.StopJanitor()
+	l.drain()
+	err = db.close()
+	l.settle(false)
+	return
+}
+
+func (db *Sqlite) close() (err error) {
 	if db.db != nil {
-		err = db.db.Close()
-		db.db = nil
-		db.stmtcache.Destroy()
+		db.stmtcache.clear()
 		db.stmtcache = nil
+		if db.keepalive != nil {
+			_ = db.keepalive.Close()
+			db.keepalive = nil
+		}
+		err = db.db.Close()
+		db.db, db.connector = nil, nil
 	}
 	return
 }
@@ -71,86 +175,95 @@ func wraptable(table string) string {
 	}
 }
 
-func (db *Sqlite) compile(q string) (*sql.Stmt, error) {
-	stmt := db.stmtcache.Get(q)
-	if stmt == nil {
-		var err error
-		stmt, err = db.db.Prepare(q)
+// compile 返回 q 的预编译语句, 用毕需 release
+func (db *Sqlite) compile(q string) (*stmtentry, error) {
+	db = db.root()
+	e := db.stmtcache.acquire(q)
+	hit := e != nil
+	if !hit {
+		stmt, err := db.db.Prepare(q)
 		if err != nil {
 			return nil, err
 		}
-		db.stmtcache.Set(q, stmt)
+		e, hit = db.stmtcache.add(q, stmt)
 	}
-	return stmt, nil
-}
-
-func (db *Sqlite) mustcompile(q string) *sql.Stmt {
-	stmt := db.stmtcache.Get(q)
-	if stmt == nil {
-		var err error
-		stmt, err = db.db.Prepare(q)
-		if err != nil {
-			panic(err)
-		}
-		db.stmtcache.Set(q, stmt)
+	if m := db.hooks.metrics; m != nil {
+		m.ObserveStmtCache(hit)
 	}
-	return stmt
+	return e, nil
 }
 
 // Exec wrap of (*sql.DB).Exec for PRAGMA settings
 func (db *Sqlite) Exec(query string, args ...any) (sql.Result, error) {
-	return db.db.Exec(query, args...)
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	r, err := db.execdirect("Exec", "", query, args...)
+	if err == nil && isddl(query) {
+		db.ClearStmtCache()
+	}
+	return r, err
 }
 
 // Create 生成数据库.
-// 默认结构体的第一个元素为主键.
+// 默认结构体的第一个元素为主键, 可用 db:",pk" 指定一个或多个主键.
+// 列约束由 db tag 声明, 见 field.constraints.
+// additional 中的 WithoutRowID 会置于表定义之后.
+// 同时建立 db:",index" 等 tag 声明的索引.
 // 返回错误.
 func (db *Sqlite) Create(table string, objptr any, additional ...string) (err error) {
-	if db.db == nil {
-		err = ErrNilDB
+	if err = db.acquire(); err != nil {
 		return
 	}
+	defer db.release()
 	var (
-		tags  = tags(objptr)
-		kinds = kinds(objptr)
-		top   = len(tags) - 1
-		cmd   = make([]string, 0, 3*(len(tags)+1))
+		tags     = tags(objptr)
+		kinds    = kinds(objptr)
+		fs       = fieldsof(reflect.TypeOf(objptr).Elem())
+		pks, imp = primarykeys(fs)
+		defs     = make([]string, 0, len(tags)+len(additional)+1)
+		suffix   = ""
 	)
-	cmd = append(cmd, "CREATE TABLE IF NOT EXISTS", wraptable(table), "(")
-	if top == 0 {
-		pk, _, _ := strings.Cut(tags[0], ",")
-		cmd = append(cmd, pk, kinds[0], "PRIMARY KEY")
-		if len(additional) > 0 {
-			cmd = append(cmd, ",")
-			cmd = append(cmd, strings.Join(additional, ","))
+	for i := range tags {
+		cons, err := fs[i].constraints(!imp || i > 0)
+		if err != nil {
+			return err
 		}
-		cmd = append(cmd, ")")
-	} else {
-		for i := range tags {
-			name, addi, hasaddi := strings.Cut(tags[i], ",")
-			cmd = append(cmd, name, kinds[i])
-			if hasaddi && i > 0 {
-				cmd = append(cmd, addi)
-			}
-			switch i {
-			default:
-				cmd = append(cmd, ",")
-			case 0:
-				cmd = append(cmd, "PRIMARY KEY,")
-			case top:
-				if len(additional) > 0 {
-					cmd = append(cmd, ",")
-					cmd = append(cmd, strings.Join(additional, ","))
-				}
-				cmd = append(cmd, ")")
-			}
+		def := tags[i] + " " + kinds[i]
+		if len(pks) == 1 && pks[0] == i {
+			def += " PRIMARY KEY"
 		}
+		if len(cons) > 0 {
+			def += " " + strings.Join(cons, " ")
+		}
+		defs = append(defs, def)
+	}
+	if len(pks) > 1 {
+		names := make([]string, len(pks))
+		for i, k := range pks {
+			names[i] = tags[k]
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(names, ", ")+")")
+	}
+	for _, a := range additional {
+		if strings.EqualFold(strings.TrimSpace(a), WithoutRowID) {
+			suffix = " " + WithoutRowID
+			continue
+		}
+		defs = append(defs, a)
 	}
-	stmt, err := db.compile(strings.Join(cmd, " ") + ";")
+	q := "CREATE TABLE IF NOT EXISTS " + db.tablename(table) + " ( " + strings.Join(defs, " , ") + " )" + suffix + ";"
+	_, err = db.exec("Create", table, q)
 	if err != nil {
-		return err
+		return
+	}
+	for _, idx := range tagindexes(table, fs) {
+		err = db.CreateIndex(idx)
+		if err != nil {
+			return
+		}
 	}
-	_, err = stmt.Exec()
 	return
 }
 
@@ -159,66 +272,7 @@ func (db *Sqlite) Create(table string, objptr any, additional ...string) (err er
 // 默认结构体的第一个元素为主键.
 // 返回错误.
 func (db *Sqlite) Insert(table string, objptr any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	table = wraptable(table)
-	stmt, err := db.compile("SELECT * FROM " + table + " limit 1;")
-	if err != nil {
-		return err
-	}
-	rows, err := stmt.Query()
-	if err != nil {
-		return err
-	}
-	if rows.Err() != nil {
-		return rows.Err()
-	}
-	tags, _ := rows.Columns()
-	rows.Close()
-	var (
-		vals = values(objptr)
-		top  = len(tags) - 1
-		cmd  = make([]string, 0, 2+4*len(tags))
-	)
-	cmd = append(cmd, "REPLACE INTO")
-	cmd = append(cmd, table)
-	if top == 0 {
-		cmd = append(cmd, "(")
-		cmd = append(cmd, tags[0])
-		cmd = append(cmd, ") VALUES ( ? )")
-	} else {
-		for i := range tags {
-			switch i {
-			default:
-				cmd = append(cmd, tags[i])
-				cmd = append(cmd, ",")
-			case 0:
-				cmd = append(cmd, "(")
-				cmd = append(cmd, tags[i])
-				cmd = append(cmd, ",")
-			case top:
-				cmd = append(cmd, tags[i])
-				cmd = append(cmd, ")")
-			}
-		}
-		for i := range tags {
-			switch i {
-			default:
-				cmd = append(cmd, "? ,")
-			case 0:
-				cmd = append(cmd, "VALUES ( ? ,")
-			case top:
-				cmd = append(cmd, "? )")
-			}
-		}
-	}
-	stmt, err = db.compile(strings.Join(cmd, " ") + ";")
-	if err != nil {
-		return err
-	}
-	_, err = stmt.Exec(vals...)
-	return err
+	return db.insert("Insert", "REPLACE INTO", table, objptr)
 }
 
 // InsertUnique 插入数据集.
@@ -226,29 +280,32 @@ func (db *Sqlite) Insert(table string, objptr any) error {
 // 默认结构体的第一个元素为主键.
 // 返回错误.
 func (db *Sqlite) InsertUnique(table string, objptr any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	table = wraptable(table)
-	stmt, err := db.compile("SELECT * FROM " + table + " limit 1;")
-	if err != nil {
+	return db.insert("InsertUnique", "INSERT INTO", table, objptr)
+}
+
+func (db *Sqlite) insert(op, verb, table string, objptr any) error {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	rows, err := stmt.Query()
+	defer db.release()
+	cols, err := db.columns(op, table)
 	if err != nil {
 		return err
 	}
-	if rows.Err() != nil {
-		return rows.Err()
-	}
-	tags, _ := rows.Columns()
-	rows.Close()
+	q, vals := insertsql(verb, db.tablename(table), cols, objptr)
+	_, err = db.exec(op, table, q, vals...)
+	return err
+}
+
+// insertsql 生成将 objptr 插入列为 cols 的 table 的语句.
+// 返回语句+参数.
+func insertsql(verb, table string, cols []string, objptr any) (string, []any) {
+	tags, vals := writable(cols, objptr)
 	var (
-		vals = values(objptr)
-		top  = len(tags) - 1
-		cmd  = make([]string, 0, 2+4*len(tags))
+		top = len(tags) - 1
+		cmd = make([]string, 0, 2+4*len(tags))
 	)
-	cmd = append(cmd, "INSERT INTO")
+	cmd = append(cmd, verb)
 	cmd = append(cmd, table)
 	if top == 0 {
 		cmd = append(cmd, "(")
@@ -280,35 +337,41 @@ func (db *Sqlite) InsertUnique(table string, objptr any) error {
 			}
 		}
 	}
-	stmt, err = db.compile(strings.Join(cmd, " ") + ";")
+	return strings.Join(cmd, " ") + ";", vals
+}
+
+// columns 返回表的所有列名
+func (db *Sqlite) columns(op, table string) ([]string, error) {
+	rows, err := db.query(op, table, "SELECT * FROM "+db.tablename(table)+" limit 1;")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = stmt.Exec(vals...)
-	return err
+	defer rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	cols, _ := rows.Columns()
+	return cols, nil
 }
 
 // Find 查询数据库，写入第一条结果到 objptr.
 // condition 可为"WHERE id = 0".
-// 默认字段与结构体元素顺序一致.
+// 默认字段与结构体元素顺序一致, 不包括 db:",expires" 字段已过期的行.
 // 返回错误.
 func (db *Sqlite) Find(table string, objptr any, condition string, questions ...any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	q := "SELECT * FROM " + wraptable(table) + " " + condition + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	q := "SELECT * FROM " + db.selectsource(table, reflect.TypeOf(objptr).Elem()) + " " + condition + ";"
+	rows, err := db.query("Find", table, q, questions...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return rows.Err()
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return ErrNullResult
@@ -325,27 +388,23 @@ func (db *Sqlite) Find(table string, objptr any, condition string, questions ...
 
 // Find 查询数据库，返回第一条结果.
 // condition 可为"WHERE id = 0".
-// 默认字段与结构体元素顺序一致.
+// 默认字段与结构体元素顺序一致, 不包括 db:",expires" 字段已过期的行.
 // 返回错误.
 func Find[T any](db *Sqlite, table string, condition string, questions ...any) (obj T, err error) {
-	if db.db == nil {
-		err = ErrNilDB
+	if err = db.acquire(); err != nil {
 		return
 	}
-	q := "SELECT * FROM " + wraptable(table) + " " + condition + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
-		return
-	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	q := "SELECT * FROM " + db.selectsource(table, reflect.TypeOf(&obj).Elem()) + " " + condition + ";"
+	rows, err := db.query("Find", table, q, questions...)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 	err = rows.Err()
 	if err != nil {
 		return
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		err = ErrNullResult
@@ -366,21 +425,18 @@ func Find[T any](db *Sqlite, table string, condition string, questions ...any) (
 // 默认字段与结构体元素顺序一致.
 // 返回错误.
 func (db *Sqlite) Query(q string, objptr any, args ...any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	rows, err := stmt.Query(args...)
+	defer db.release()
+	rows, err := db.query("Query", "", q, args...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return rows.Err()
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return ErrNullResult
@@ -400,23 +456,19 @@ func (db *Sqlite) Query(q string, objptr any, args ...any) error {
 // 默认字段与结构体元素顺序一致.
 // 返回错误.
 func Query[T any](db *Sqlite, q string, args ...any) (obj T, err error) {
-	if db.db == nil {
-		err = ErrNilDB
+	if err = db.acquire(); err != nil {
 		return
 	}
-	stmt, err := db.compile(q)
-	if err != nil {
-		return
-	}
-	rows, err := stmt.Query(args...)
+	defer db.release()
+	rows, err := db.query("Query", "", q, args...)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 	err = rows.Err()
 	if err != nil {
 		return
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		err = ErrNullResult
@@ -434,25 +486,33 @@ func Query[T any](db *Sqlite, q string, args ...any) (obj T, err error) {
 
 // CanFind 查询数据库是否有 condition.
 // condition 可为"WHERE id = 0".
-// 默认字段与结构体元素顺序一致.
+// 默认字段与结构体元素顺序一致, 包括已过期的行, 需要排除时用 CanFind[T].
 // 返回错误.
 func (db *Sqlite) CanFind(table string, condition string, questions ...any) bool {
-	if db.db == nil {
-		return false
-	}
-	q := "SELECT * FROM " + wraptable(table) + " " + condition + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
+	return db.canfind(table, db.tablename(table), condition, questions...)
+}
+
+// CanFind 查询数据库是否有 condition, 不包括 T 的 db:",expires" 字段已过期的行.
+// condition 可为"WHERE id = 0".
+func CanFind[T any](db *Sqlite, table string, condition string, questions ...any) bool {
+	return db.canfind(table, db.selectsource(table, reflect.TypeOf((*T)(nil)).Elem()), condition, questions...)
+}
+
+// canfind 查询 source 中是否有 condition
+func (db *Sqlite) canfind(table, source, condition string, questions ...any) bool {
+	if db.acquire() != nil {
 		return false
 	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	q := "SELECT * FROM " + source + " " + condition + ";"
+	rows, err := db.query("CanFind", table, q, questions...)
 	if err != nil {
 		return false
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return false
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return false
@@ -466,21 +526,18 @@ func (db *Sqlite) CanFind(table string, condition string, questions ...any) bool
 // 默认字段与结构体元素顺序一致.
 // 返回错误.
 func (db *Sqlite) CanQuery(q string, questions ...any) bool {
-	if db.db == nil {
+	if db.acquire() != nil {
 		return false
 	}
-	stmt, err := db.compile(q)
-	if err != nil {
-		return false
-	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	rows, err := db.query("CanQuery", "", q, questions...)
 	if err != nil {
 		return false
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return false
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return false
@@ -491,25 +548,22 @@ func (db *Sqlite) CanQuery(q string, questions ...any) bool {
 
 // FindFor 查询数据库，用函数 f 遍历结果.
 // condition 可为"WHERE id = 0".
-// 默认字段与结构体元素顺序一致.
+// 默认字段与结构体元素顺序一致, 不包括 db:",expires" 字段已过期的行.
 // 返回错误.
 func (db *Sqlite) FindFor(table string, objptr any, condition string, f func() error, questions ...any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	q := "SELECT * FROM " + wraptable(table) + " " + condition + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	q := "SELECT * FROM " + db.selectsource(table, reflect.TypeOf(objptr).Elem()) + " " + condition + ";"
+	rows, err := db.query("FindFor", table, q, questions...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return rows.Err()
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return ErrNullResult
@@ -532,47 +586,15 @@ func (db *Sqlite) FindFor(table string, objptr any, condition string, f func() e
 
 // FindAll 查询数据库，返回多个结果.
 // condition 可为"WHERE id = 0".
-// 默认字段与结构体元素顺序一致.
+// 默认字段与结构体元素顺序一致, 不包括 db:",expires" 字段已过期的行.
 // 返回错误.
 func FindAll[T any](db *Sqlite, table string, condition string, questions ...any) ([]*T, error) {
-	if db.db == nil {
-		return nil, ErrNilDB
-	}
-	q := "SELECT * FROM " + wraptable(table) + " " + condition + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
-		return nil, err
-	}
-	rows, err := stmt.Query(questions...)
-	if err != nil {
-		return nil, err
-	}
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
-	defer rows.Close()
-
-	if !rows.Next() {
-		return nil, ErrNullResult
-	}
-	vals := make([]*T, 1, 64)
-	var v T
-	err = rows.Scan(addrs(&v)...)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return nil, err
 	}
-	vals[0] = &v
-	for rows.Next() {
-		if err != nil {
-			return nil, err
-		}
-		var v T
-		err = rows.Scan(addrs(&v)...)
-		if err == nil {
-			vals = append(vals, &v)
-		}
-	}
-	return vals, nil
+	defer db.release()
+	q := "SELECT * FROM " + db.selectsource(table, reflect.TypeOf((*T)(nil)).Elem()) + " " + condition + ";"
+	return queryall[T](db, "FindAll", table, q, questions...)
 }
 
 // QueryFor 查询数据库，用函数 f 遍历结果.
@@ -580,21 +602,18 @@ func FindAll[T any](db *Sqlite, table string, condition string, questions ...any
 // 默认字段与结构体元素顺序一致.
 // 返回错误.
 func (db *Sqlite) QueryFor(q string, objptr any, f func() error, questions ...any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	rows, err := db.query("QueryFor", "", q, questions...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return rows.Err()
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return ErrNullResult
@@ -620,21 +639,27 @@ func (db *Sqlite) QueryFor(q string, objptr any, f func() error, questions ...an
 // 默认字段与结构体元素顺序一致.
 // 返回错误.
 func QueryAll[T any](db *Sqlite, q string, questions ...any) ([]*T, error) {
-	if db.db == nil {
-		return nil, ErrNilDB
-	}
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(questions...)
+	defer db.release()
+	return queryall[T](db, "QueryAll", "", q, questions...)
+}
+
+// queryall 执行 q 并返回所有结果, op 与 table 用于上报执行信息
+func queryall[T any](db *Sqlite, op, table, q string, questions ...any) ([]*T, error) {
+	return scanall[T](db.query(op, table, q, questions...))
+}
+
+// scanall 读取 rows 中的所有结果
+func scanall[T any](rows *rows, err error) ([]*T, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
-	defer rows.Close()
 
 	if !rows.Next() {
 		return nil, ErrNullResult
@@ -661,34 +686,37 @@ func QueryAll[T any](db *Sqlite, q string, questions ...any) ([]*T, error) {
 
 // Pick 从 table 随机一行
 func (db *Sqlite) Pick(table string, objptr any, questions ...any) error {
-	if db.db == nil {
-		return ErrNilDB
+	if err := db.acquire(); err != nil {
+		return err
 	}
+	defer db.release()
 	return db.Find(table, objptr, "ORDER BY RANDOM() limit 1", questions...)
 }
 
 // PickFor 从 table 随机多行
 func (db *Sqlite) PickFor(table string, n uint, objptr any, f func() error, questions ...any) error {
-	if db.db == nil {
-		return ErrNilDB
+	if err := db.acquire(); err != nil {
+		return err
 	}
+	defer db.release()
 	return db.FindFor(table, objptr, "ORDER BY RANDOM() limit "+strconv.Itoa(int(n)), f, questions...)
 }
 
 // ListTables 列出所有表名
 // 返回所有表名+错误
 func (db *Sqlite) ListTables() (s []string, err error) {
-	if db.db == nil {
-		return nil, ErrNilDB
+	if err := db.acquire(); err != nil {
+		return nil, err
 	}
-	rows, err := db.mustcompile("SELECT name FROM sqlite_master where type='table' order by name;").Query()
+	defer db.release()
+	rows, err := db.query("ListTables", "", "SELECT name FROM sqlite_master where type='table' order by name;")
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
-	defer rows.Close()
 
 	for rows.Next() {
 		if err != nil {
@@ -707,70 +735,56 @@ func (db *Sqlite) ListTables() (s []string, err error) {
 // condition 可为"WHERE id = 0".
 // 返回错误.
 func (db *Sqlite) Del(table string, condition string, questions ...any) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	q := "DELETE FROM " + wraptable(table) + " " + condition + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	_, err = stmt.Exec(questions...)
+	defer db.release()
+	q := "DELETE FROM " + db.tablename(table) + " " + condition + ";"
+	_, err := db.exec("Del", table, q, questions...)
 	return err
 }
 
 // Drop 删除数据库表
 func (db *Sqlite) Drop(table string) error {
-	if db.db == nil {
-		return ErrNilDB
-	}
-	q := "DROP TABLE " + wraptable(table) + ";"
-	stmt, err := db.compile(q)
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return err
 	}
-	_, err = stmt.Exec()
+	defer db.release()
+	q := "DROP TABLE " + db.tablename(table) + ";"
+	_, err := db.exec("Drop", table, q)
+	if err == nil {
+		db.invalidatetable(table)
+	}
 	return err
 }
 
 // Count 查询数据库行数.
 // 返回行数以及错误.
 func (db *Sqlite) Count(table string) (num int, err error) {
-	if db.db == nil {
-		return 0, ErrNilDB
-	}
-	stmt, err := db.compile("SELECT COUNT(1) FROM " + wraptable(table) + ";")
-	if err != nil {
+	if err := db.acquire(); err != nil {
 		return 0, err
 	}
-	rows, err := stmt.Query()
+	defer db.release()
+	rows, err := db.query("Count", table, "SELECT COUNT(1) FROM "+db.tablename(table)+";")
 	if err != nil {
 		return 0, err
 	}
+	defer rows.Close()
 	if rows.Err() != nil {
 		return 0, rows.Err()
 	}
 	if rows.Next() {
 		err = rows.Scan(&num)
 	}
-	rows.Close()
 	return num, err
 }
 
-// tags 反射 返回结构体对象的 tag 数组
+// tags 反射 返回结构体对象的列名数组
 func tags(objptr any) (tags []string) {
-	elem := reflect.ValueOf(objptr).Elem()
-	flen := elem.Type().NumField()
-	tags = make([]string, flen)
-	for i := 0; i < flen; i++ {
-		t := elem.Type().Field(i).Tag.Get("db")
-		if t == "" {
-			t = elem.Type().Field(i).Tag.Get("json")
-			if t == "" {
-				t = elem.Type().Field(i).Name
-			}
-		}
-		tags[i] = t
+	fs := fieldsof(reflect.TypeOf(objptr).Elem())
+	tags = make([]string, len(fs))
+	for i, f := range fs {
+		tags[i] = f.name
 	}
 	return
 }
@@ -778,14 +792,11 @@ func tags(objptr any) (tags []string) {
 // kinds 反射 返回结构体对象的 kinds 数组
 func kinds(objptr any) (kinds []string) {
 	elem := reflect.ValueOf(objptr).Elem()
-	// 判断第一个元素是否为匿名字段
-	if elem.Type().Field(0).Anonymous {
-		elem = elem.Field(0)
-	}
-	flen := elem.Type().NumField()
-	kinds = make([]string, flen)
-	for i := 0; i < flen; i++ {
-		typ := elem.Field(i).Type().String()
+	fs := fieldsof(elem.Type())
+	kinds = make([]string, len(fs))
+	for i, f := range fs {
+		fv := elem.FieldByIndex(f.index)
+		typ := fv.Type().String()
 		switch typ {
 		case "bool", "*bool":
 			kinds[i] = "BOOLEAN"
@@ -816,10 +827,10 @@ func kinds(objptr any) (kinds []string) {
 		case "string", "[]string", "*string", "*[]string":
 			kinds[i] = "TEXT"
 		default:
-			k := elem.Field(i).Kind()
+			k := fv.Kind()
 			if k == reflect.Interface || k == reflect.Pointer {
 				typ = "*"
-				k = elem.Field(i).Elem().Kind()
+				k = fv.Elem().Kind()
 			}
 			switch k {
 			case reflect.Bool:
@@ -870,14 +881,15 @@ var typstrarr = reflect.SliceOf(reflect.TypeOf(""))
 // values 反射 返回结构体对象的 values 数组
 func values(objptr any) (values []any) {
 	elem := reflect.ValueOf(objptr).Elem()
-	flen := elem.Type().NumField()
-	values = make([]any, flen)
-	for i := 0; i < flen; i++ {
-		if elem.Field(i).Type() == typstrarr { // []string
-			values[i] = elem.Field(i).Index(0).Interface() // string
+	fs := fieldsof(elem.Type())
+	values = make([]any, len(fs))
+	for i, f := range fs {
+		fv := elem.FieldByIndex(f.index)
+		if fv.Type() == typstrarr { // []string
+			values[i] = fv.Index(0).Interface() // string
 			continue
 		}
-		values[i] = elem.Field(i).Interface()
+		values[i] = fv.Interface()
 	}
 	return
 }
@@ -885,16 +897,17 @@ func values(objptr any) (values []any) {
 // addrs 反射 返回结构体对象的 addrs 数组
 func addrs(objptr any) (addrs []any) {
 	elem := reflect.ValueOf(objptr).Elem()
-	flen := elem.Type().NumField()
-	addrs = make([]any, flen)
-	for i := 0; i < flen; i++ {
-		if elem.Field(i).Type() == typstrarr { // []string
+	fs := fieldsof(elem.Type())
+	addrs = make([]any, len(fs))
+	for i, f := range fs {
+		fv := elem.FieldByIndex(f.index)
+		if fv.Type() == typstrarr { // []string
 			s := reflect.ValueOf(make([]string, 1))
-			elem.Field(i).Set(s)
+			fv.Set(s)
 			addrs[i] = s.Index(0).Addr().Interface() // string
 			continue
 		}
-		addrs[i] = elem.Field(i).Addr().Interface()
+		addrs[i] = fv.Addr().Interface()
 	}
 	return
 }
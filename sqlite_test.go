@@ -2,6 +2,7 @@ package sql
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"strconv"
 	"testing"
@@ -267,3 +268,270 @@ func TestAdditionalTags(t *testing.T) {
 		t.Fatal("unexpected insert")
 	}
 }
+
+func TestSkipAndReadonlyTags(t *testing.T) {
+	type base struct {
+		ID *int
+	}
+	type counter struct {
+		base
+		Count   uint
+		Created string `db:",readonly,DEFAULT 'now'"`
+		Cache   []int  `db:"-"`
+		private int
+	}
+	_ = os.Remove("test.db")
+	db := Sqlite{dbpath: "test.db"}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("counter", &counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("counter", &counter{Count: 1, Created: "ignored", Cache: []int{1}, private: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Find[counter](&db, "counter", "WHERE Count=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *c.ID != 1 || c.Created != "now" || c.Cache != nil || c.private != 0 {
+		t.Fatal("unexpected", *c.ID, c.Created, c.Cache, c.private)
+	}
+}
+
+func TestTagConstraints(t *testing.T) {
+	type teacher struct {
+		ID   *int
+		Name string `db:"Name,collate=nocase,check=length(Name) > 0"`
+	}
+	type class struct {
+		ID           *int
+		TeacherID    int    `db:"TeacherID,references=teacher(ID),on_delete=cascade"`
+		StudentCount int    `db:"StudentCount,default=abs(-30)"`
+		Title        string `db:"Title,generated='class of ' || StudentCount stored"`
+	}
+	_ = os.Remove("test.db")
+	db := Sqlite{dbpath: "test.db"}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("PRAGMA foreign_keys = ON;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("teacher", &teacher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("class", &class{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("teacher", &teacher{Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("teacher", &teacher{})
+	if err == nil {
+		t.Fatal("unexpected check pass")
+	}
+	if !db.CanFind("teacher", "WHERE Name = 'ANNA'") {
+		t.Fatal("collate nocase not applied")
+	}
+	err = db.Insert("class", &class{TeacherID: 1, StudentCount: 66})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Find[class](&db, "class", "WHERE TeacherID = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Title != "class of 66" {
+		t.Fatal("unexpected generated column", c.Title)
+	}
+	_, err = db.Exec("INSERT INTO class (TeacherID) VALUES (1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.CanFind("class", "WHERE StudentCount = 30") {
+		t.Fatal("default not applied")
+	}
+	err = db.Insert("class", &class{TeacherID: 2})
+	if err == nil {
+		t.Fatal("unexpected fk pass")
+	}
+	err = db.Del("teacher", "WHERE ID = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Count("class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatal("cascade not applied")
+	}
+	type bad struct {
+		ID   *int
+		Name string `db:"Name,on_delete=cascade"`
+	}
+	err = db.Create("bad", &bad{})
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Fatal("unexpected", err)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	type member struct {
+		Nick    string
+		GroupID int64 `db:"GroupID,pk"`
+		User    int64 `db:"User,pk"`
+	}
+	type score struct {
+		Value int
+		ID    int64 `db:"ID,pk"`
+	}
+	_ = os.Remove("test.db")
+	db := Sqlite{dbpath: "test.db"}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("member", &member{}, WithoutRowID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("score", &score{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		err = db.Insert("member", &member{Nick: "a" + strconv.Itoa(i), GroupID: 1, User: int64(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.InsertUnique("member", &member{Nick: "dup", GroupID: 1, User: 1})
+	if err == nil {
+		t.Fatal("unexpected insert")
+	}
+	err = db.Update("member", &member{Nick: "b2", GroupID: 1, User: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update("member", &member{Nick: "none", GroupID: 2, User: 2})
+	if err != ErrNullResult {
+		t.Fatal("unexpected", err)
+	}
+	m, err := Get[member](&db, "member", int64(1), int64(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Nick != "b2" {
+		t.Fatal("unexpected", m.Nick)
+	}
+	_, err = Get[member](&db, "member", int64(1))
+	if err != ErrKeyCount {
+		t.Fatal("unexpected", err)
+	}
+	m = member{GroupID: 1, User: 3}
+	err = db.Get("member", &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Nick != "a3" {
+		t.Fatal("unexpected", m.Nick)
+	}
+	err = db.DelByKey("member", &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Count("member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatal("unexpected count", n)
+	}
+	err = db.Insert("score", &score{Value: 1, ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("score", &score{Value: 2, ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Get[score](&db, "score", int64(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Value != 2 {
+		t.Fatal("unexpected", s.Value)
+	}
+}
+
+func TestIndexTags(t *testing.T) {
+	type user struct {
+		ID      *int
+		Name    string `db:"Name,index"`
+		GroupID int64  `db:"GroupID,unique_index=uq_member"`
+		QQ      int64  `db:"QQ,unique_index=uq_member WHERE Deleted = 0"`
+		Deleted bool
+	}
+	_ = os.Remove("test.db")
+	db := Sqlite{dbpath: "test.db"}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.CreateIndex(Index{Name: "idx_deleted", Table: "user", Columns: []string{"Deleted"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idxs, err := db.ListIndexes("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idxs) != 3 {
+		t.Fatal("unexpected", idxs)
+	}
+	if idxs[0].Name != "idx_deleted" || idxs[1].Name != "idx_user_Name" || idxs[1].Unique {
+		t.Fatal("unexpected", idxs)
+	}
+	uq := idxs[2]
+	if uq.Name != "uq_member" || !uq.Unique || len(uq.Columns) != 2 || uq.Columns[1] != "QQ" || uq.Where != "Deleted = 0" {
+		t.Fatal("unexpected", uq)
+	}
+	err = db.Insert("user", &user{Name: "a", GroupID: 1, QQ: 1, Deleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert("user", &user{Name: "a", GroupID: 1, QQ: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.InsertUnique("user", &user{Name: "a", GroupID: 1, QQ: 1})
+	if err == nil {
+		t.Fatal("unexpected insert")
+	}
+	err = db.DropIndex("idx_deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idxs, err = db.ListIndexes("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idxs) != 2 {
+		t.Fatal("unexpected", idxs)
+	}
+}
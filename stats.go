@@ -0,0 +1,89 @@
+package sql
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// ObjectStats 表或索引的空间占用
+type ObjectStats struct {
+	Name    string
+	Type    string // table 或 index
+	Table   string // 所属表, 表自身时与 Name 相同
+	Rows    int64  // 行数, 仅对表统计
+	Pages   int64
+	Bytes   int64 // 占用的页字节数
+	Payload int64 // 其中实际数据的字节数
+}
+
+// Stats 数据库统计信息
+type Stats struct {
+	PageSize      int64
+	PageCount     int64
+	FreelistCount int64
+	FileBytes     int64 // 数据库文件大小
+	WALBytes      int64 // WAL 文件大小
+	// Objects 按 Bytes 从大到小排序
+	Objects []ObjectStats
+	// Estimated 为 true 时 dbstat 不可用,
+	// 表的 Bytes 按行数比例估算, Pages 与 Payload 为 0, 索引不计.
+	Estimated bool
+}
+
+// Stats 返回数据库的页, 文件与各表和索引的空间占用统计.
+// 返回统计信息+错误.
+func (db *Sqlite) Stats() (*Stats, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+	s := &Stats{}
+	var err error
+	s.PageSize, s.PageCount, s.FreelistCount, err = db.pagestat("Stats")
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(db.root().dbpath); err == nil {
+		s.FileBytes = fi.Size()
+	}
+	s.WALBytes = db.walsize()
+	type object struct {
+		Name    string
+		Type    string
+		Table   string
+		Pages   int64
+		Bytes   int64
+		Payload int64
+	}
+	objs, err := queryall[object](db, "Stats", "", "SELECT s.name, coalesce(m.type, 'table'), coalesce(m.tbl_name, s.name), "+
+		"s.pageno, s.pgsize, s.payload FROM dbstat AS s LEFT JOIN sqlite_master AS m ON m.name = s.name WHERE s.aggregate = TRUE;")
+	if err != nil && strings.Contains(err.Error(), "dbstat") {
+		s.Estimated = true
+		objs, err = queryall[object](db, "Stats", "", "SELECT name, type, tbl_name, 0, 0, 0 FROM sqlite_master WHERE type = 'table';")
+	}
+	if err != nil && err != ErrNullResult {
+		return nil, err
+	}
+	var total int64
+	s.Objects = make([]ObjectStats, len(objs))
+	for i, o := range objs {
+		s.Objects[i] = ObjectStats{Name: o.Name, Type: o.Type, Table: o.Table, Pages: o.Pages, Bytes: o.Bytes, Payload: o.Payload}
+		if o.Type != "table" || strings.HasPrefix(o.Name, "sqlite_") {
+			continue
+		}
+		err = db.scanrow("Stats", o.Name, "SELECT COUNT(1) FROM "+wraptable(o.Name)+";", &s.Objects[i].Rows)
+		if err != nil {
+			return nil, err
+		}
+		total += s.Objects[i].Rows
+	}
+	if s.Estimated && total > 0 {
+		used := (s.PageCount - s.FreelistCount) * s.PageSize
+		for i := range s.Objects {
+			s.Objects[i].Bytes = used * s.Objects[i].Rows / total
+		}
+	}
+	sort.SliceStable(s.Objects, func(i, j int) bool { return s.Objects[i].Bytes > s.Objects[j].Bytes })
+	return s, nil
+}
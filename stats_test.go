@@ -0,0 +1,58 @@
+package sql
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	type msg struct {
+		ID   *int
+		Text string `db:"Text,index"`
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := Sqlite{dbpath: dbpath}
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, table := range []string{"123", "456"} {
+		err = db.Create(table, &msg{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 512; i++ {
+		err = db.Insert("123", &msg{Text: strings.Repeat(strconv.Itoa(i), 64)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Insert("456", &msg{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PageSize == 0 || s.PageCount == 0 || s.FileBytes != s.PageSize*s.PageCount {
+		t.Fatal("unexpected", s)
+	}
+	top := s.Objects[0]
+	if s.Estimated || top.Table != "123" || top.Bytes == 0 {
+		t.Fatal("unexpected", s.Objects)
+	}
+	for _, o := range s.Objects {
+		if o.Name == "456" && o.Rows != 1 {
+			t.Fatal("unexpected", o)
+		}
+		if o.Name == "idx_123_Text" && (o.Type != "index" || o.Table != "123") {
+			t.Fatal("unexpected", o)
+		}
+	}
+}
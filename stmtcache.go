@@ -0,0 +1,227 @@
+package sql
+
+import (
+	"container/list"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStmtCacheSize 默认最多缓存的语句数
+const DefaultStmtCacheSize = 256
+
+// StmtCacheStats 语句缓存状态
+type StmtCacheStats struct {
+	Size      int // 当前缓存的语句数
+	Capacity  int
+	Hits      int64
+	Misses    int64
+	Evictions int64 // 因容量, 过期或失效被移出的语句数
+}
+
+// stmtcache 按 LRU 淘汰, 闲置超过 ttl 后过期的预编译语句缓存.
+// 被移出时仍在使用的语句在最后一次 release 后关闭.
+type stmtcache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	cap   int
+	ll    *list.List // 从新到旧, 闲置越久越靠后, 也即越早过期
+	items map[string]*list.Element
+
+	hits, misses, evictions int64
+
+	onevict func() // 移出一条语句时调用
+}
+
+type stmtentry struct {
+	c       *stmtcache
+	q       string
+	stmt    *sql.Stmt
+	exp     time.Time
+	refs    int
+	removed bool
+}
+
+func newstmtcache(ttl time.Duration, capacity int, onevict func()) *stmtcache {
+	if capacity <= 0 {
+		capacity = DefaultStmtCacheSize
+	}
+	return &stmtcache{
+		ttl: ttl, cap: capacity, ll: list.New(),
+		items: make(map[string]*list.Element), onevict: onevict,
+	}
+}
+
+// acquire 取出 q 的语句并增加引用, 未命中时返回 nil.
+// 只计命中, 未命中由随后的 add 计数, 以免一次查找计两次.
+func (c *stmtcache) acquire(q string) *stmtentry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.expire(now)
+	el, ok := c.items[q]
+	if !ok {
+		return nil
+	}
+	c.hits++
+	e := el.Value.(*stmtentry)
+	e.exp = now.Add(c.ttl)
+	e.refs++
+	c.ll.MoveToFront(el)
+	return e
+}
+
+// add 缓存 q 的语句并增加引用, 计一次未命中.
+// q 已被其它调用缓存时关闭 stmt 并返回已有的语句, 计一次命中.
+// 返回语句+是否命中.
+func (c *stmtcache) add(q string, stmt *sql.Stmt) (*stmtentry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if el, ok := c.items[q]; ok {
+		_ = stmt.Close()
+		c.hits++
+		e := el.Value.(*stmtentry)
+		e.exp = now.Add(c.ttl)
+		e.refs++
+		c.ll.MoveToFront(el)
+		return e, true
+	}
+	c.misses++
+	e := &stmtentry{c: c, q: q, stmt: stmt, exp: now.Add(c.ttl), refs: 1}
+	c.items[q] = c.ll.PushFront(e)
+	for c.ll.Len() > c.cap {
+		c.remove(c.ll.Back())
+	}
+	c.expire(now)
+	return e, false
+}
+
+// release 减少引用, 已被移出的语句在无引用时关闭
+func (e *stmtentry) release() {
+	e.c.mu.Lock()
+	defer e.c.mu.Unlock()
+	e.refs--
+	if e.removed && e.refs == 0 {
+		_ = e.stmt.Close()
+	}
+}
+
+// expire 移出所有已过期的语句
+func (c *stmtcache) expire(now time.Time) {
+	for el := c.ll.Back(); el != nil && now.After(el.Value.(*stmtentry).exp); el = c.ll.Back() {
+		c.remove(el)
+	}
+}
+
+func (c *stmtcache) remove(el *list.Element) {
+	e := el.Value.(*stmtentry)
+	c.ll.Remove(el)
+	delete(c.items, e.q)
+	e.removed = true
+	if e.refs == 0 {
+		_ = e.stmt.Close()
+	}
+	c.evictions++
+	if c.onevict != nil {
+		c.onevict()
+	}
+}
+
+// invalidate 移出所有满足 f 的语句
+func (c *stmtcache) invalidate(f func(q string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if f(el.Value.(*stmtentry).q) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// clear 移出所有语句
+func (c *stmtcache) clear() {
+	c.invalidate(func(string) bool { return true })
+}
+
+func (c *stmtcache) stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return StmtCacheStats{
+		Size: c.ll.Len(), Capacity: c.cap,
+		Hits: c.hits, Misses: c.misses, Evictions: c.evictions,
+	}
+}
+
+// WithStmtCacheSize 设置最多缓存的语句数, 超出时淘汰最久未用的语句.
+// n <= 0 时为 DefaultStmtCacheSize.
+func WithStmtCacheSize(n int) Option {
+	return func(db *Sqlite) {
+		db.stmtcachesize = n
+	}
+}
+
+// ClearStmtCache 清空语句缓存
+func (db *Sqlite) ClearStmtCache() {
+	if db.acquire() != nil {
+		return
+	}
+	defer db.release()
+	db = db.root()
+	db.stmtcache.clear()
+}
+
+// StmtCacheStats 返回语句缓存状态
+func (db *Sqlite) StmtCacheStats() StmtCacheStats {
+	if db.acquire() != nil {
+		return StmtCacheStats{}
+	}
+	defer db.release()
+	db = db.root()
+	return db.stmtcache.stats()
+}
+
+// invalidatetable 移出所有引用了 table 的语句
+func (db *Sqlite) invalidatetable(table string) {
+	db = db.root()
+	if db.stmtcache == nil {
+		return
+	}
+	db.stmtcache.invalidate(func(q string) bool { return referencestable(q, table) })
+}
+
+// referencestable 判断 q 中是否有与 table 相同的标识符
+func referencestable(q, table string) bool {
+	if table == "" {
+		return false
+	}
+	q, table = strings.ToLower(q), strings.ToLower(table)
+	for i := 0; ; {
+		j := strings.Index(q[i:], table)
+		if j < 0 {
+			return false
+		}
+		j += i
+		end := j + len(table)
+		if (j == 0 || !isidentbyte(q[j-1])) && (end == len(q) || !isidentbyte(q[end])) {
+			return true
+		}
+		i = j + 1
+	}
+}
+
+// isddl 判断 q 是否会修改表结构
+func isddl(q string) bool {
+	f := strings.Fields(q)
+	if len(f) == 0 {
+		return false
+	}
+	switch strings.ToUpper(f[0]) {
+	case "ALTER", "DROP", "CREATE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStmtCache(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := New(dbpath, WithStmtCacheSize(3))
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create("other", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_ = db.Find("user", &user{}, "WHERE ID="+strconv.Itoa(i))
+	}
+	st := db.StmtCacheStats()
+	if st.Size != 3 || st.Capacity != 3 || st.Evictions < 7 {
+		t.Fatal("cache not bounded", st)
+	}
+	_ = db.Find("user", &user{}, "WHERE ID=9")
+	if db.StmtCacheStats().Hits != st.Hits+1 {
+		t.Fatal("expected a hit", db.StmtCacheStats())
+	}
+
+	_, _ = db.Count("other")
+	err = db.Drop("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st = db.StmtCacheStats(); st.Size != 1 {
+		t.Fatal("statements of dropped table not invalidated", st)
+	}
+	db.ClearStmtCache()
+	if st = db.StmtCacheStats(); st.Size != 0 {
+		t.Fatal("cache not cleared", st)
+	}
+	_, err = db.Count("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReferencesTable(t *testing.T) {
+	for _, c := range []struct {
+		q, table string
+		want     bool
+	}{
+		{"SELECT * FROM 'user' WHERE ID=1;", "user", true},
+		{"SELECT * FROM [123];", "123", true},
+		{"SELECT * FROM users;", "user", false},
+		{"SELECT * FROM 'user_log';", "user", false},
+		{"select * from USER join b", "user", true},
+	} {
+		if got := referencestable(c.q, c.table); got != c.want {
+			t.Fatal(c.q, c.table, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package sql
+
+import "context"
+
+// Tracer 为每条语句开启 span, 可桥接到 OpenTelemetry 等追踪系统
+type Tracer interface {
+	// Start 在语句执行前调用, e 中已有 Op, Table, SQL 与 Args.
+	// 返回的 ctx 用于执行语句并传给 Logger.
+	Start(ctx context.Context, e *QueryEvent) (context.Context, Span)
+}
+
+// Span 一条语句的 span
+type Span interface {
+	// End 在语句结束后调用, e 中已有 Duration, Rows 与 Err
+	End(e *QueryEvent)
+}
+
+// WithTracer 用 t 追踪每条语句
+func WithTracer(t Tracer) Option {
+	return func(db *Sqlite) {
+		db.hooks.tracer = t
+	}
+}
+
+// WithContext 返回以 ctx 执行语句的 db 视图.
+// ctx 会传给 Tracer 与 Logger, 取消时中断进行中的语句.
+// 视图的所有状态都来自 db, Restore 等操作后仍可使用. 在视图上启动的后台任务属于 db, 视图的 Close 不做任何事.
+func (db *Sqlite) WithContext(ctx context.Context) *Sqlite {
+	return &Sqlite{parent: db.root(), ctx: ctx}
+}
+
+// root 返回视图所属的 db, db 不是视图时返回自身
+func (db *Sqlite) root() *Sqlite {
+	if db.parent != nil {
+		return db.parent
+	}
+	return db
+}
+
+// context 返回执行语句使用的 ctx
+func (db *Sqlite) context() context.Context {
+	if db.ctx == nil {
+		return context.Background()
+	}
+	return db.ctx
+}
@@ -0,0 +1,139 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testspan struct {
+	parent string
+	e      *QueryEvent
+	ended  bool
+}
+
+type testtracer struct {
+	spans []*testspan
+}
+
+type tracekey struct{}
+
+func (t *testtracer) Start(ctx context.Context, e *QueryEvent) (context.Context, Span) {
+	parent, _ := ctx.Value(tracekey{}).(string)
+	s := &testspan{parent: parent}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (s *testspan) End(e *QueryEvent) {
+	s.e, s.ended = e, true
+}
+
+func TestTracer(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	tr := &testtracer{}
+	dbpath := filepath.Join(t.TempDir(), "test.db")
+	db := New(dbpath, WithTracer(tr))
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), tracekey{}, "command")
+	err = db.WithContext(ctx).Insert("user", &user{ID: 1, Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FindAll[user](db.WithContext(ctx), "user", "WHERE ID = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := tr.spans[len(tr.spans)-1]
+	if !last.ended || last.parent != "command" {
+		t.Fatal("span not propagated from ctx", last)
+	}
+	if last.e.Op != "FindAll" || last.e.Table != "user" || last.e.Rows != 1 || last.e.SQL == "" {
+		t.Fatal("unexpected span annotations", last.e)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = db.WithContext(canceled).Find("user", &user{}, "WHERE ID = 1")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+	last = tr.spans[len(tr.spans)-1]
+	if !last.ended || !errors.Is(last.e.Err, context.Canceled) {
+		t.Fatal("error not recorded on span", last.e)
+	}
+}
+
+func TestContextView(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	dir := t.TempDir()
+	db := New(filepath.Join(dir, "test.db"))
+	err := db.Open(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	v := db.WithContext(context.Background())
+	err = v.Create("user", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Insert("user", &user{ID: 1, Name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Backup(filepath.Join(dir, "backup.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Insert("user", &user{ID: 2, Name: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Restore 替换连接后视图仍可用
+	err = db.Restore(filepath.Join(dir, "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := v.Count("user")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	// 视图上启动的后台任务属于 db
+	err = v.StartMaintenance(MaintenanceOptions{Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.life.tasks.Lock()
+	m := db.maintainer
+	db.life.tasks.Unlock()
+	if m == nil {
+		t.Fatal("maintenance not started on db")
+	}
+	v.StopMaintenance()
+	// 视图的 Close 不关闭 db
+	err = v.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = db.Count("user")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+}